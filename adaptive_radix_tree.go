@@ -0,0 +1,486 @@
+package go_tries
+
+import "strings"
+
+// Kinds of ART nodes. Inner nodes grow to the next kind when full and
+// shrink to the previous one when sparse.
+const (
+	artLeaf = iota
+	artNode4
+	artNode16
+	artNode48
+	artNode256
+)
+
+// Fewest children an inner node keeps before it shrinks
+const (
+	artMin16  = 4
+	artMin48  = 13
+	artMin256 = 38
+)
+
+// AdaptiveRadixTree is an Adaptive Radix Tree (ART) as described by Leis,
+// Kemper and Neumann. Inner nodes come in four sizes picked by their
+// number of children, single key subtrees are stored as a leaf holding the
+// whole key (lazy expansion) and runs of single child nodes are collapsed
+// into a prefix (path compression).
+type AdaptiveRadixTree[V any] struct {
+	// Root node or nil when the tree is empty
+	root *artNode[V]
+	// Number of keys stored
+	size int
+}
+
+type artNode[V any] struct {
+	// One of artLeaf, artNode4, artNode16, artNode48 or artNode256
+	kind uint8
+	// Full key and value of a leaf
+	key   string
+	value V
+	// Compressed path of an inner node
+	prefix string
+	// Leaf of the key ending at an inner node
+	end *artNode[V]
+	// Number of children
+	num int
+	// Sorted child bytes for Node4 and Node16, or the child slot plus one
+	// of every byte for Node48
+	keys []byte
+	// Children. Node256 indexes them by byte.
+	children []*artNode[V]
+}
+
+// NewAdaptiveRadixTree allocates and returns a new *AdaptiveRadixTree.
+func NewAdaptiveRadixTree[V any]() *AdaptiveRadixTree[V] {
+	return &AdaptiveRadixTree[V]{}
+}
+
+func newArtLeaf[V any](key string, value V) *artNode[V] {
+	return &artNode[V]{kind: artLeaf, key: key, value: value}
+}
+
+func newArtNode4[V any](prefix string) *artNode[V] {
+	return &artNode[V]{
+		kind:     artNode4,
+		prefix:   prefix,
+		keys:     make([]byte, 4),
+		children: make([]*artNode[V], 4),
+	}
+}
+
+// Returns the slot holding the child at byte ch or nil
+func (n *artNode[V]) findChild(ch byte) **artNode[V] {
+	switch n.kind {
+	case artNode4, artNode16:
+		for i := 0; i < n.num; i++ {
+			if n.keys[i] == ch {
+				return &n.children[i]
+			}
+		}
+	case artNode48:
+		if slot := n.keys[ch]; slot != 0 {
+			return &n.children[slot-1]
+		}
+	case artNode256:
+		if n.children[ch] != nil {
+			return &n.children[ch]
+		}
+	}
+	return nil
+}
+
+// Adds child at byte ch, growing the node when it is full
+func (n *artNode[V]) addChild(ch byte, child *artNode[V]) {
+	if n.num == len(n.children) && n.kind != artNode256 {
+		n.grow()
+	}
+
+	switch n.kind {
+	case artNode4, artNode16:
+		i := 0
+		for i < n.num && n.keys[i] < ch {
+			i++
+		}
+		copy(n.keys[i+1:n.num+1], n.keys[i:n.num])
+		copy(n.children[i+1:n.num+1], n.children[i:n.num])
+		n.keys[i] = ch
+		n.children[i] = child
+	case artNode48:
+		slot := 0
+		for n.children[slot] != nil {
+			slot++
+		}
+		n.children[slot] = child
+		n.keys[ch] = byte(slot + 1)
+	case artNode256:
+		n.children[ch] = child
+	}
+	n.num++
+}
+
+// Removes the child at byte ch, shrinking the node when it is sparse
+func (n *artNode[V]) removeChild(ch byte) {
+	switch n.kind {
+	case artNode4, artNode16:
+		i := 0
+		for n.keys[i] != ch {
+			i++
+		}
+		copy(n.keys[i:], n.keys[i+1:n.num])
+		copy(n.children[i:], n.children[i+1:n.num])
+		n.children[n.num-1] = nil
+	case artNode48:
+		n.children[n.keys[ch]-1] = nil
+		n.keys[ch] = 0
+	case artNode256:
+		n.children[ch] = nil
+	}
+	n.num--
+
+	switch {
+	case n.kind == artNode16 && n.num < artMin16,
+		n.kind == artNode48 && n.num < artMin48,
+		n.kind == artNode256 && n.num < artMin256:
+		n.shrink()
+	}
+}
+
+// Moves the children of n into the next bigger kind
+func (n *artNode[V]) grow() {
+	switch n.kind {
+	case artNode4:
+		keys := make([]byte, 16)
+		children := make([]*artNode[V], 16)
+		copy(keys, n.keys)
+		copy(children, n.children)
+		n.kind, n.keys, n.children = artNode16, keys, children
+	case artNode16:
+		keys := make([]byte, 256)
+		children := make([]*artNode[V], 48)
+		for i := 0; i < n.num; i++ {
+			keys[n.keys[i]] = byte(i + 1)
+			children[i] = n.children[i]
+		}
+		n.kind, n.keys, n.children = artNode48, keys, children
+	case artNode48:
+		children := make([]*artNode[V], 256)
+		for ch, slot := range n.keys {
+			if slot != 0 {
+				children[ch] = n.children[slot-1]
+			}
+		}
+		n.kind, n.keys, n.children = artNode256, nil, children
+	}
+}
+
+// Moves the children of n into the next smaller kind
+func (n *artNode[V]) shrink() {
+	switch n.kind {
+	case artNode16:
+		n.kind, n.keys, n.children = artNode4, n.keys[:4:4], n.children[:4:4]
+	case artNode48:
+		keys := make([]byte, 16)
+		children := make([]*artNode[V], 16)
+		i := 0
+		for ch, slot := range n.keys {
+			if slot != 0 {
+				keys[i] = byte(ch)
+				children[i] = n.children[slot-1]
+				i++
+			}
+		}
+		n.kind, n.keys, n.children = artNode16, keys, children
+	case artNode256:
+		keys := make([]byte, 256)
+		children := make([]*artNode[V], 48)
+		slot := 0
+		for ch, child := range n.children {
+			if child != nil {
+				children[slot] = child
+				keys[ch] = byte(slot + 1)
+				slot++
+			}
+		}
+		n.kind, n.keys, n.children = artNode48, keys, children
+	}
+}
+
+// Calls fn for every child in ascending byte order until it returns false
+func (n *artNode[V]) eachChild(fn func(child *artNode[V]) bool) bool {
+	switch n.kind {
+	case artNode4, artNode16:
+		for i := 0; i < n.num; i++ {
+			if !fn(n.children[i]) {
+				return false
+			}
+		}
+	case artNode48:
+		for _, slot := range n.keys {
+			if slot != 0 && !fn(n.children[slot-1]) {
+				return false
+			}
+		}
+	case artNode256:
+		for _, child := range n.children {
+			if child != nil && !fn(child) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Puts leaf below n, either as its end or as the child at its next byte
+func (n *artNode[V]) attach(leaf *artNode[V], depth int) {
+	if depth == len(leaf.key) {
+		n.end = leaf
+	} else {
+		n.addChild(leaf.key[depth], leaf)
+	}
+}
+
+// Len returns the number of keys stored in the tree.
+func (tree *AdaptiveRadixTree[V]) Len() int {
+	return tree.size
+}
+
+// Get returns the value stored at the given key and whether the key was
+// found. It does not allocate.
+func (tree *AdaptiveRadixTree[V]) Get(key string) (V, bool) {
+	var zero V
+	n := tree.root
+	depth := 0
+	for n != nil {
+		if n.kind == artLeaf {
+			if n.key == key {
+				return n.value, true
+			}
+			return zero, false
+		}
+
+		if !strings.HasPrefix(key[depth:], n.prefix) {
+			return zero, false
+		}
+		depth += len(n.prefix)
+
+		if depth == len(key) {
+			if n.end != nil {
+				return n.end.value, true
+			}
+			return zero, false
+		}
+
+		child := n.findChild(key[depth])
+		if child == nil {
+			return zero, false
+		}
+		n = *child
+		depth++
+	}
+	return zero, false
+}
+
+// Lookup returns the value stored at the given key or ErrKeyNotFound if
+// the key is not stored.
+func (tree *AdaptiveRadixTree[V]) Lookup(key string) (V, error) {
+	value, ok := tree.Get(key)
+	if !ok {
+		return value, notFound(key)
+	}
+	return value, nil
+}
+
+// Insert stores value at the given key. Every key can be stored, so it
+// always returns nil.
+func (tree *AdaptiveRadixTree[V]) Insert(key string, value V) error {
+	tree.Put(key, value)
+	return nil
+}
+
+// Put stores value at the given key. It returns the previous value and
+// whether it was replaced.
+func (tree *AdaptiveRadixTree[V]) Put(key string, value V) (V, bool) {
+	old, replaced := tree.insert(&tree.root, key, value, 0)
+	if !replaced {
+		tree.size += 1
+	}
+	return old, replaced
+}
+
+func (tree *AdaptiveRadixTree[V]) insert(ref **artNode[V], key string, value V, depth int) (V, bool) {
+	var zero V
+	n := *ref
+	if n == nil {
+		*ref = newArtLeaf(key, value)
+		return zero, false
+	}
+
+	if n.kind == artLeaf {
+		if n.key == key {
+			old := n.value
+			n.value = value
+			return old, true
+		}
+
+		// Expand the leaf into a node holding both keys
+		length := commonPrefixLen(n.key[depth:], key[depth:])
+		node := newArtNode4[V](key[depth : depth+length])
+		node.attach(n, depth+length)
+		node.attach(newArtLeaf(key, value), depth+length)
+		*ref = node
+		return zero, false
+	}
+
+	length := commonPrefixLen(n.prefix, key[depth:])
+	if length < len(n.prefix) {
+		// Split the prefix where key leaves it
+		node := newArtNode4[V](n.prefix[:length])
+		node.addChild(n.prefix[length], n)
+		n.prefix = n.prefix[length+1:]
+		node.attach(newArtLeaf(key, value), depth+length)
+		*ref = node
+		return zero, false
+	}
+	depth += len(n.prefix)
+
+	if depth == len(key) {
+		if n.end != nil {
+			old := n.end.value
+			n.end.value = value
+			return old, true
+		}
+		n.end = newArtLeaf(key, value)
+		return zero, false
+	}
+
+	child := n.findChild(key[depth])
+	if child == nil {
+		n.addChild(key[depth], newArtLeaf(key, value))
+		return zero, false
+	}
+	return tree.insert(child, key, value, depth+1)
+}
+
+// Delete removes the given key. It returns the removed value and whether
+// the key was found.
+func (tree *AdaptiveRadixTree[V]) Delete(key string) (V, bool) {
+	old, ok := tree.remove(&tree.root, key, 0)
+	if ok {
+		tree.size -= 1
+	}
+	return old, ok
+}
+
+func (tree *AdaptiveRadixTree[V]) remove(ref **artNode[V], key string, depth int) (V, bool) {
+	var zero V
+	n := *ref
+	if n == nil {
+		return zero, false
+	}
+
+	if n.kind == artLeaf {
+		if n.key != key {
+			return zero, false
+		}
+		*ref = nil
+		return n.value, true
+	}
+
+	if !strings.HasPrefix(key[depth:], n.prefix) {
+		return zero, false
+	}
+	depth += len(n.prefix)
+
+	if depth == len(key) {
+		if n.end == nil {
+			return zero, false
+		}
+		old := n.end.value
+		n.end = nil
+		collapse(ref)
+		return old, true
+	}
+
+	child := n.findChild(key[depth])
+	if child == nil {
+		return zero, false
+	}
+
+	if (*child).kind != artLeaf {
+		return tree.remove(child, key, depth+1)
+	}
+
+	if (*child).key != key {
+		return zero, false
+	}
+	old := (*child).value
+	n.removeChild(key[depth])
+	collapse(ref)
+	return old, true
+}
+
+// Replaces a Node4 left with a single key or child by that key or child
+func collapse[V any](ref **artNode[V]) {
+	n := *ref
+	if n.kind != artNode4 {
+		return
+	}
+
+	switch {
+	case n.num == 0:
+		*ref = n.end
+	case n.num == 1 && n.end == nil:
+		child := n.children[0]
+		if child.kind != artLeaf {
+			child.prefix = n.prefix + string(n.keys[:1]) + child.prefix
+		}
+		*ref = child
+	}
+}
+
+// WalkPrefix calls fn for every key that starts with prefix, until fn
+// returns false. Keys are visited in ascending byte order.
+func (tree *AdaptiveRadixTree[V]) WalkPrefix(prefix string, fn func(key string, value V) bool) {
+	n := tree.root
+	depth := 0
+	for n != nil {
+		if n.kind == artLeaf {
+			if strings.HasPrefix(n.key, prefix) {
+				fn(n.key, n.value)
+			}
+			return
+		}
+
+		rest := prefix[depth:]
+		if len(rest) <= len(n.prefix) {
+			if strings.HasPrefix(n.prefix, rest) {
+				n.walk(fn)
+			}
+			return
+		}
+		if !strings.HasPrefix(rest, n.prefix) {
+			return
+		}
+		depth += len(n.prefix)
+
+		child := n.findChild(prefix[depth])
+		if child == nil {
+			return
+		}
+		n = *child
+		depth++
+	}
+}
+
+// Visits the keys below n in order. Returns false if fn stopped the walk.
+func (n *artNode[V]) walk(fn func(key string, value V) bool) bool {
+	if n.kind == artLeaf {
+		return fn(n.key, n.value)
+	}
+	if n.end != nil && !fn(n.end.key, n.end.value) {
+		return false
+	}
+	return n.eachChild(func(child *artNode[V]) bool {
+		return child.walk(fn)
+	})
+}
@@ -0,0 +1,188 @@
+package go_tries
+
+import (
+	mrand "math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestAdaptiveRadixTreeGrowAndShrink(t *testing.T) {
+	a := NewAdaptiveRadixTree[int]()
+
+	kinds := map[int]int{1: artNode4, 4: artNode4, 5: artNode16, 16: artNode16, 17: artNode48, 48: artNode48, 49: artNode256, 256: artNode256}
+	for i := 0; i < 256; i++ {
+		a.Put("ka"+string([]byte{byte(i)}), i)
+		if kind, ok := kinds[i+1]; ok && i > 0 && a.root.kind != uint8(kind) {
+			t.Errorf("expected node kind for %v children to be %v, got %v", i+1, kind, a.root.kind)
+		}
+	}
+
+	if a.root.prefix != "ka" {
+		t.Errorf("expected compressed prefix %q, got %q", "ka", a.root.prefix)
+	}
+
+	for i := 0; i < 256; i++ {
+		key := "ka" + string([]byte{byte(i)})
+		if value, ok := a.Get(key); value != i || ok != true {
+			t.Fatalf("expected Get for %q to be %v, got %v", key, i, value)
+		}
+	}
+
+	for i := 255; i > 0; i-- {
+		a.Delete("ka" + string([]byte{byte(i)}))
+		if i == 37 && a.root.kind != artNode48 {
+			t.Errorf("expected node kind for %v children to be %v, got %v", i, artNode48, a.root.kind)
+		}
+		if i == 12 && a.root.kind != artNode16 {
+			t.Errorf("expected node kind for %v children to be %v, got %v", i, artNode16, a.root.kind)
+		}
+		if i == 3 && a.root.kind != artNode4 {
+			t.Errorf("expected node kind for %v children to be %v, got %v", i, artNode4, a.root.kind)
+		}
+	}
+
+	// A single key collapses back into a leaf
+	if a.root.kind != artLeaf || a.root.key != "ka\x00" {
+		t.Errorf("expected root to be the leaf %q, got kind %v", "ka\x00", a.root.kind)
+	}
+}
+
+func TestAdaptiveRadixTreePrefixKeys(t *testing.T) {
+	a := NewAdaptiveRadixTree[int]()
+
+	keys := []string{"", "a", "ab", "abc", "abd", "b"}
+	for i, key := range keys {
+		a.Put(key, i)
+	}
+
+	for i, key := range keys {
+		if value, ok := a.Get(key); value != i || ok != true {
+			t.Errorf("expected Get for %q to be %v, got %v", key, i, value)
+		}
+	}
+
+	for _, key := range []string{"abcd", "ac", "c"} {
+		if _, ok := a.Get(key); ok != false {
+			t.Errorf("expected Get for %q to be %v, got %v", key, false, true)
+		}
+	}
+
+	if value, ok := a.Delete("ab"); value != 2 || ok != true {
+		t.Errorf("expected Delete for %q to be %v, got %v", "ab", 2, value)
+	}
+
+	var walked []string
+	a.WalkPrefix("a", func(key string, value int) bool {
+		walked = append(walked, key)
+		return true
+	})
+	if !reflect.DeepEqual(walked, []string{"a", "abc", "abd"}) {
+		t.Errorf("expected WalkPrefix to yield %v, got %v", []string{"a", "abc", "abd"}, walked)
+	}
+}
+
+func TestAdaptiveRadixTreeCollapseHighByte(t *testing.T) {
+	a := NewAdaptiveRadixTree[int]()
+
+	// Deleting "xy" merges the node below 0xff into the root, whose prefix
+	// must take 0xff as a single byte
+	keys := []string{"x\xffab", "x\xffac", "xy"}
+	for i, key := range keys {
+		a.Put(key, i)
+	}
+	a.Delete("xy")
+
+	if a.root.prefix != "x\xffa" {
+		t.Errorf("expected compressed prefix %q, got %q", "x\xffa", a.root.prefix)
+	}
+	for i, key := range keys[:2] {
+		if value, ok := a.Get(key); value != i || ok != true {
+			t.Errorf("expected Get for %q to be %v, got %v", key, i, value)
+		}
+	}
+}
+
+func TestAdaptiveRadixTreeRandomKeys(t *testing.T) {
+	r := mrand.New(mrand.NewSource(5))
+	a := NewAdaptiveRadixTree[string]()
+	ref := make(map[string]string)
+
+	for i := 0; i < 20000; i++ {
+		key := string(randomKeyFrom(r, "abcdefghijklmnopqrstuvwxyz0123456789", 4))
+		if r.Intn(3) == 0 {
+			_, ok := a.Delete(key)
+			if _, exists := ref[key]; ok != exists {
+				t.Fatalf("expected Delete for %q to be %v, got %v", key, exists, ok)
+			}
+			delete(ref, key)
+		} else {
+			a.Put(key, key)
+			ref[key] = key
+		}
+	}
+
+	if a.Len() != len(ref) {
+		t.Errorf("expected Len to be %v, got %v", len(ref), a.Len())
+	}
+
+	var expected []string
+	for key := range ref {
+		expected = append(expected, key)
+		if value, ok := a.Get(key); value != key || ok != true {
+			t.Errorf("expected Get for %q to be %q, got %q", key, key, value)
+		}
+	}
+	sort.Strings(expected)
+
+	var keys []string
+	a.WalkPrefix("", func(key string, value string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected WalkPrefix to yield %v keys in order, got %v", len(expected), len(keys))
+	}
+}
+
+func BenchmarkAdaptiveRadixTreePutStringKey(b *testing.B) {
+	trie := NewAdaptiveRadixTree[int]()
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trie.Put(words[i%len(words)], i)
+	}
+}
+
+func BenchmarkAdaptiveRadixTreeGetStringKey(b *testing.B) {
+	trie := NewAdaptiveRadixTree[int]()
+	for i := 0; i < b.N; i++ {
+		trie.Put(words[i%len(words)], i)
+	}
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trie.Get(words[i%len(words)])
+	}
+}
+
+func BenchmarkAdaptiveRadixTreePutPhraseKey(b *testing.B) {
+	trie := NewAdaptiveRadixTree[int]()
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trie.Put(phrases[i%len(phrases)], i)
+	}
+}
+
+func BenchmarkAdaptiveRadixTreeGetPhraseKey(b *testing.B) {
+	trie := NewAdaptiveRadixTree[int]()
+	for i := 0; i < b.N; i++ {
+		trie.Put(phrases[i%len(phrases)], i)
+	}
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trie.Get(phrases[i%len(phrases)])
+	}
+}
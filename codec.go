@@ -0,0 +1,86 @@
+package go_tries
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+)
+
+// ValueCodec encodes the values of a trie when it is serialized. SimpleTrie
+// encodes its values together in the order it visits them, so a codec can
+// share work such as type information across all of them. DoubleArrayTrie
+// encodes every value on its own so a mapped file decodes only the values
+// it returns.
+type ValueCodec[V any] interface {
+	// EncodeValues writes values to w.
+	EncodeValues(w io.Writer, values []V) error
+	// DecodeValues reads the n values written by EncodeValues from r.
+	DecodeValues(r io.Reader, n int) ([]V, error)
+}
+
+// SingleValueCodec is implemented by codecs that can encode one value
+// without the framing EncodeValues needs for a list of them.
+// DoubleArrayTrie uses it when the codec implements it since it encodes
+// every value on its own, and calls EncodeValues and DecodeValues with a
+// single value otherwise.
+type SingleValueCodec[V any] interface {
+	// EncodeValue writes value to w.
+	EncodeValue(w io.Writer, value V) error
+	// DecodeValue reads the value written by EncodeValue from r.
+	DecodeValue(r io.Reader) (V, error)
+}
+
+// Encodes value on its own with codec
+func encodeValue[V any](codec ValueCodec[V], w io.Writer, value V) error {
+	if single, ok := codec.(SingleValueCodec[V]); ok {
+		return single.EncodeValue(w, value)
+	}
+	return codec.EncodeValues(w, []V{value})
+}
+
+// Decodes a value encoded by encodeValue with codec
+func decodeValue[V any](codec ValueCodec[V], r io.Reader) (V, error) {
+	if single, ok := codec.(SingleValueCodec[V]); ok {
+		return single.DecodeValue(r)
+	}
+	values, err := codec.DecodeValues(r, 1)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	return values[0], nil
+}
+
+// GobCodec encodes values with encoding/gob. It is the codec tries use
+// unless another one is set. Concrete types stored in interface values
+// have to be registered with gob.Register.
+type GobCodec[V any] struct{}
+
+// EncodeValues writes values to w as a gob stream.
+func (GobCodec[V]) EncodeValues(w io.Writer, values []V) error {
+	return gob.NewEncoder(w).Encode(values)
+}
+
+// DecodeValues reads n values written by EncodeValues from r.
+func (GobCodec[V]) DecodeValues(r io.Reader, n int) ([]V, error) {
+	var values []V
+	if err := gob.NewDecoder(r).Decode(&values); err != nil {
+		return nil, err
+	}
+	if len(values) != n {
+		return nil, fmt.Errorf("go_tries: decoded %d values, expected %d", len(values), n)
+	}
+	return values, nil
+}
+
+// EncodeValue writes value to w as a gob stream.
+func (GobCodec[V]) EncodeValue(w io.Writer, value V) error {
+	return gob.NewEncoder(w).Encode(&value)
+}
+
+// DecodeValue reads a value written by EncodeValue from r.
+func (GobCodec[V]) DecodeValue(r io.Reader) (V, error) {
+	var value V
+	err := gob.NewDecoder(r).Decode(&value)
+	return value, err
+}
@@ -0,0 +1,158 @@
+package go_tries
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Tx is the view of a trie inside ConcurrentTrie.Update.
+type Tx[V any] interface {
+	Trie[V]
+}
+
+// ConcurrentTrie makes a Trie safe for concurrent use. Any number of
+// goroutines may read at once while writes are serialized, as with a
+// sync.RWMutex.
+type ConcurrentTrie[V any] struct {
+	mu   sync.RWMutex
+	trie Trie[V]
+}
+
+// Concurrent wraps trie for concurrent use. The trie must not be used
+// directly afterwards.
+func Concurrent[V any](trie Trie[V]) *ConcurrentTrie[V] {
+	return &ConcurrentTrie[V]{trie: trie}
+}
+
+// Get returns the value stored at the given key and whether the key was
+// found.
+func (c *ConcurrentTrie[V]) Get(key string) (V, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.trie.Get(key)
+}
+
+// Put stores value at the given key. It returns the previous value and
+// whether it was replaced.
+func (c *ConcurrentTrie[V]) Put(key string, value V) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.trie.Put(key, value)
+}
+
+// Delete removes the given key. It returns the removed value and whether
+// the key was found.
+func (c *ConcurrentTrie[V]) Delete(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.trie.Delete(key)
+}
+
+// Lookup returns the value stored at the given key or an error wrapping
+// ErrKeyNotFound. Errors of a wrapped CheckedTrie are passed on.
+func (c *ConcurrentTrie[V]) Lookup(key string) (V, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if checked, ok := c.trie.(CheckedTrie[V]); ok {
+		return checked.Lookup(key)
+	}
+	value, ok := c.trie.Get(key)
+	if !ok {
+		return value, notFound(key)
+	}
+	return value, nil
+}
+
+// Insert stores value at the given key. Errors of a wrapped CheckedTrie are
+// passed on, and keys a KeyValidator rejects return ErrInvalidKey.
+func (c *ConcurrentTrie[V]) Insert(key string, value V) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if checked, ok := c.trie.(CheckedTrie[V]); ok {
+		return checked.Insert(key, value)
+	}
+	if validator, ok := c.trie.(KeyValidator); ok && !validator.ValidKey(key) {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	c.trie.Put(key, value)
+	return nil
+}
+
+// Len returns the number of keys stored in the trie.
+func (c *ConcurrentTrie[V]) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.trie.Len()
+}
+
+// Update calls fn with exclusive access to the trie, so readers see either
+// none or all of its changes. When fn returns an error or panics, its
+// changes are undone and the error or panic is passed on. The Tx must not
+// be used after fn returns.
+func (c *ConcurrentTrie[V]) Update(fn func(tx Tx[V]) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tx := &undoTx[V]{trie: c.trie}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.rollback()
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// A change made in a transaction and how to undo it
+type undoRecord[V any] struct {
+	key string
+	// Value the key held before the change and whether it held one
+	old    V
+	hadOld bool
+}
+
+// Tx recording every change so that it can be undone
+type undoTx[V any] struct {
+	trie Trie[V]
+	log  []undoRecord[V]
+}
+
+func (tx *undoTx[V]) Get(key string) (V, bool) {
+	return tx.trie.Get(key)
+}
+
+func (tx *undoTx[V]) Put(key string, value V) (V, bool) {
+	old, replaced := tx.trie.Put(key, value)
+	tx.log = append(tx.log, undoRecord[V]{key: key, old: old, hadOld: replaced})
+	return old, replaced
+}
+
+func (tx *undoTx[V]) Delete(key string) (V, bool) {
+	old, ok := tx.trie.Delete(key)
+	if ok {
+		tx.log = append(tx.log, undoRecord[V]{key: key, old: old, hadOld: true})
+	}
+	return old, ok
+}
+
+func (tx *undoTx[V]) Len() int {
+	return tx.trie.Len()
+}
+
+// Undoes the changes in reverse order
+func (tx *undoTx[V]) rollback() {
+	for i := len(tx.log) - 1; i >= 0; i-- {
+		record := tx.log[i]
+		if record.hadOld {
+			tx.trie.Put(record.key, record.old)
+		} else {
+			tx.trie.Delete(record.key)
+		}
+	}
+	tx.log = nil
+}
@@ -0,0 +1,217 @@
+package go_tries
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// Returns a new empty trie of every type
+func newTries() map[string]Trie[int] {
+	return map[string]Trie[int]{
+		"SimpleTrie":        NewSimpleTrie[int](),
+		"DoubleArrayTrie":   NewDoubleArrayTrie[int](),
+		"RadixTree":         NewRadixTree[int](),
+		"AdaptiveRadixTree": NewAdaptiveRadixTree[int](),
+		"TernarySearchTree": NewTernarySearchTree[int](),
+	}
+}
+
+func TestConcurrentTrie(t *testing.T) {
+	const goroutines = 8
+	const keys = 200
+
+	for name, trie := range newTries() {
+		c := Concurrent(trie)
+
+		var wg sync.WaitGroup
+		for g := 0; g < goroutines; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < keys; i++ {
+					key := fmt.Sprintf("g%d key %d", g, i)
+					c.Put(key, i)
+					c.Get(fmt.Sprintf("g%d key %d", (g+1)%goroutines, i))
+					c.Len()
+					if i%2 == 1 {
+						c.Delete(key)
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+
+		if c.Len() != goroutines*keys/2 {
+			t.Errorf("%s: expected Len to be %v, got %v", name, goroutines*keys/2, c.Len())
+		}
+		for g := 0; g < goroutines; g++ {
+			for i := 0; i < keys; i++ {
+				value, ok := c.Get(fmt.Sprintf("g%d key %d", g, i))
+				if ok != (i%2 == 0) || (ok && value != i) {
+					t.Fatalf("%s: expected Get for %q to be (%v, %v), got (%v, %v)", name, fmt.Sprintf("g%d key %d", g, i), i, i%2 == 0, value, ok)
+				}
+			}
+		}
+	}
+}
+
+func TestConcurrentTrieChecked(t *testing.T) {
+	d := NewDoubleArrayTrie[int]()
+	tries := map[string]Trie[int]{
+		"CheckedTrie": d,
+		// Hides Insert and Lookup but not ValidKey
+		"KeyValidator": struct {
+			Trie[int]
+			KeyValidator
+		}{d, d},
+	}
+
+	for name, trie := range tries {
+		c := Concurrent(trie)
+		if err := c.Insert("a", 1); err != nil {
+			t.Errorf("%s: expected Insert to succeed, got %v", name, err)
+		}
+		if err := c.Insert("a\x00b", 2); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%s: expected Insert to fail with %v, got %v", name, ErrInvalidKey, err)
+		}
+		if value, err := c.Lookup("a"); value != 1 || err != nil {
+			t.Errorf("%s: expected Lookup for %q to be %v, got (%v, %v)", name, "a", 1, value, err)
+		}
+		if _, err := c.Lookup("b"); !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("%s: expected Lookup to fail with %v, got %v", name, ErrKeyNotFound, err)
+		}
+		if c.Len() != 1 {
+			t.Errorf("%s: expected Len to be %v, got %v", name, 1, c.Len())
+		}
+	}
+}
+
+func TestConcurrentTrieUpdateIsAtomic(t *testing.T) {
+	for name, trie := range newTries() {
+		c := Concurrent(trie)
+		c.Put("key 0", 0)
+
+		// Every update moves the single key, so readers always see one
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 1; i < 500; i++ {
+				c.Update(func(tx Tx[int]) error {
+					tx.Delete(fmt.Sprintf("key %d", i-1))
+					tx.Put(fmt.Sprintf("key %d", i), i)
+					return nil
+				})
+			}
+		}()
+
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 500; j++ {
+					if n := c.Len(); n != 1 {
+						t.Errorf("%s: expected Len to be %v, got %v", name, 1, n)
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+func TestConcurrentTrieUpdateRollback(t *testing.T) {
+	errAbort := errors.New("abort")
+
+	for name, trie := range newTries() {
+		c := Concurrent(trie)
+		c.Put("kept", 1)
+		c.Put("replaced", 2)
+		c.Put("deleted", 3)
+
+		err := c.Update(func(tx Tx[int]) error {
+			tx.Put("replaced", 20)
+			tx.Put("added", 4)
+			tx.Delete("deleted")
+			tx.Delete("missing")
+			tx.Put("added", 40)
+			return errAbort
+		})
+		if err != errAbort {
+			t.Errorf("%s: expected Update to return %v, got %v", name, errAbort, err)
+		}
+
+		expected := map[string]int{"kept": 1, "replaced": 2, "deleted": 3}
+		if c.Len() != len(expected) {
+			t.Errorf("%s: expected Len to be %v, got %v", name, len(expected), c.Len())
+		}
+		for key, value := range expected {
+			if got, ok := c.Get(key); got != value || ok != true {
+				t.Errorf("%s: expected Get for %q to be (%v, %v), got (%v, %v)", name, key, value, true, got, ok)
+			}
+		}
+		if _, ok := c.Get("added"); ok {
+			t.Errorf("%s: expected Get for %q to be %v, got %v", name, "added", false, ok)
+		}
+	}
+}
+
+func TestConcurrentTrieUpdatePanic(t *testing.T) {
+	c := Concurrent[int](NewSimpleTrie[int]())
+	c.Put("kept", 1)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected Update to pass the panic on")
+			}
+		}()
+		c.Update(func(tx Tx[int]) error {
+			tx.Delete("kept")
+			panic("boom")
+		})
+	}()
+
+	// The lock was released and the change undone
+	if value, ok := c.Get("kept"); value != 1 || ok != true {
+		t.Errorf("expected Get for %q to be (%v, %v), got (%v, %v)", "kept", 1, true, value, ok)
+	}
+}
+
+func BenchmarkConcurrentTrieGetParallel(b *testing.B) {
+	for name, trie := range newTries() {
+		c := Concurrent(trie)
+		for i, word := range words {
+			c.Put(word, i)
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					c.Get(words[i%len(words)])
+					i++
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkConcurrentTriePutParallel(b *testing.B) {
+	for name, trie := range newTries() {
+		c := Concurrent(trie)
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					c.Put(words[i%len(words)], i)
+					i++
+				}
+			})
+		})
+	}
+}
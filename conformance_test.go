@@ -0,0 +1,48 @@
+package go_tries_test
+
+import (
+	"testing"
+
+	go_tries "github.com/theodesp/go-tries"
+	"github.com/theodesp/go-tries/triestest"
+)
+
+// Returns a SimpleTrie splitting keys into bytes, as triestest expects
+func newByteSimpleTrie() go_tries.Trie[any] {
+	return go_tries.NewSimpleTrie[any](go_tries.WithTokenizer(go_tries.ByteTokenizer{}))
+}
+
+func TestConformance(t *testing.T) {
+	tries := map[string]func() go_tries.Trie[any]{
+		"SimpleTrie":        newByteSimpleTrie,
+		"DoubleArrayTrie":   func() go_tries.Trie[any] { return go_tries.NewDoubleArrayTrie[any]() },
+		"RadixTree":         func() go_tries.Trie[any] { return go_tries.NewRadixTree[any]() },
+		"AdaptiveRadixTree": func() go_tries.Trie[any] { return go_tries.NewAdaptiveRadixTree[any]() },
+		"TernarySearchTree": func() go_tries.Trie[any] { return go_tries.NewTernarySearchTree[any]() },
+	}
+	for name, newTrie := range tries {
+		t.Run(name, func(t *testing.T) {
+			triestest.RunConformance(t, newTrie)
+		})
+	}
+}
+
+func TestConcurrentConformance(t *testing.T) {
+	tries := map[string]func() go_tries.Trie[any]{
+		"ConcurrentTrie": func() go_tries.Trie[any] {
+			return go_tries.Concurrent(newByteSimpleTrie())
+		},
+		"PersistentRadixTree": func() go_tries.Trie[any] { return go_tries.NewPersistentRadixTree[any]() },
+		"ShardedTrie": func() go_tries.Trie[any] {
+			return go_tries.NewShardedTrie[any](go_tries.WithShardOptions(go_tries.WithTokenizer(go_tries.ByteTokenizer{})))
+		},
+		"ShardedTriePrefix": func() go_tries.Trie[any] {
+			return go_tries.NewShardedTrie[any](go_tries.WithShardPrefix(2), go_tries.WithShardOptions(go_tries.WithTokenizer(go_tries.ByteTokenizer{})))
+		},
+	}
+	for name, newTrie := range tries {
+		t.Run(name, func(t *testing.T) {
+			triestest.RunConcurrentConformance(t, newTrie)
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package go_tries
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A state with several arcs tries at most maxTries holes more than
+// searchWindow positions below the highest used one, and each hole is tried
+// for at most maxTries states
+const (
+	maxTries     = 255
+	searchWindow = 4096
+)
+
+// A state of the trie under construction together with the range of keys
+// below it, which share their first depth bytes.
+type dartRange struct {
+	s      int
+	depth  int
+	lo, hi int
+}
+
+// Builds the base and check arrays of a DoubleArrayTrie from sorted keys
+type dartBuilder[V any] struct {
+	d      *DoubleArrayTrie[V]
+	keys   []string
+	values []V
+	// Tail segments of the leaves, numbered from 1 in the order they were
+	// placed
+	rests []string
+	// Disjoint sets over positions where every position points at the next
+	// free position not before it. holes also skips the positions that
+	// failed too many states.
+	free  []int
+	holes []int
+	// Number of states that did not fit at every position
+	tries []uint8
+	// Highest used position
+	top int
+}
+
+// BuildDoubleArray builds a DoubleArrayTrie holding keys with their values.
+// Keys must be sorted in ascending order without duplicates and valid for
+// ValidKey, otherwise an error wrapping ErrInvalidKey is returned. States are
+// laid out breadth first and every group of arcs is placed at the first
+// free positions that fit it, which is much faster than calling Put for
+// every key. The result answers every query as one built with Put would
+// and can still be modified.
+func BuildDoubleArray[V any](keys []string, values []V) (*DoubleArrayTrie[V], error) {
+	if len(keys) != len(values) {
+		return nil, errors.New("go_tries: keys and values differ in length")
+	}
+	for i, key := range keys {
+		if !validKey(key) {
+			return nil, fmt.Errorf("%w: %q holds the terminator byte", ErrInvalidKey, key)
+		}
+		if i > 0 && keys[i-1] >= key {
+			return nil, fmt.Errorf("%w: %q is not sorted after %q", ErrInvalidKey, key, keys[i-1])
+		}
+	}
+
+	b := &dartBuilder[V]{
+		d:      NewDoubleArrayTrie[V](),
+		keys:   keys,
+		values: values,
+	}
+	if len(keys) == 0 {
+		return b.d, nil
+	}
+
+	queue := []dartRange{{s: 1, lo: 0, hi: len(keys)}}
+	for len(queue) > 0 {
+		r := queue[0]
+		queue = queue[1:]
+		queue = b.expand(r, queue)
+	}
+	if cells := max(len(b.d.base), len(b.d.check)); cells > maxPosition {
+		return nil, fmt.Errorf("%w: %d positions needed", ErrCapacity, cells)
+	}
+
+	b.d.tail, b.d.tails = packTails(b.rests)
+	if uint64(len(b.d.tail)) > maxTailLen {
+		return nil, fmt.Errorf("%w: %d tail bytes needed", ErrCapacity, len(b.d.tail))
+	}
+	b.d.size = len(keys)
+	return b.d, nil
+}
+
+// Places the arcs of the state of r and returns queue with the ranges of
+// its inner children appended
+func (b *dartBuilder[V]) expand(r dartRange, queue []dartRange) []dartRange {
+	// Split the range into groups of keys sharing the code at depth. The
+	// keys are sorted so the codes ascend and groups are contiguous.
+	var codes []int
+	var starts []int
+	for i := r.lo; i < r.hi; i += 1 {
+		ch := codeAt(b.keys[i], r.depth)
+		if len(codes) == 0 || codes[len(codes)-1] != ch {
+			codes = append(codes, ch)
+			starts = append(starts, i)
+		}
+	}
+	starts = append(starts, r.hi)
+
+	base := b.findBase(codes)
+	b.d.setBase(r.s, base)
+
+	for i, ch := range codes {
+		t := base + ch
+		b.d.setCheck(t, r.s)
+		b.use(t)
+
+		lo, hi := starts[i], starts[i+1]
+		if hi-lo > 1 {
+			queue = append(queue, dartRange{s: t, depth: r.depth + 1, lo: lo, hi: hi})
+			continue
+		}
+
+		// A single key continues in the tail
+		b.rests = append(b.rests, restAt(b.keys[lo], r.depth))
+		b.d.setBase(t, -len(b.rests))
+		b.d.setValue(t, b.values[lo])
+	}
+	return queue
+}
+
+// Returns the first position not before pos that set does not skip.
+// Every position of set points at such a position not before it.
+func nextIn(set *[]int, pos int) int {
+	for len(*set) <= pos {
+		*set = append(*set, len(*set))
+	}
+
+	root := pos
+	for (*set)[root] != root {
+		root = (*set)[root]
+		for len(*set) <= root {
+			*set = append(*set, len(*set))
+		}
+	}
+
+	// Compress the path to the position
+	for (*set)[pos] != root {
+		pos, (*set)[pos] = (*set)[pos], root
+	}
+	return root
+}
+
+// Marks pos as used
+func (b *dartBuilder[V]) use(pos int) {
+	nextIn(&b.free, pos)
+	b.free[pos] = pos + 1
+	nextIn(&b.holes, pos)
+	b.holes[pos] = pos + 1
+	b.top = max(b.top, pos)
+}
+
+// Reports whether every code of codes lands on a free position when the
+// first one lands on pos
+func (b *dartBuilder[V]) fits(pos int, codes []int) bool {
+	base := pos - codes[0]
+	for _, ch := range codes[1:] {
+		if !b.d.isFree(base + ch) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a base such that every code of codes lands on a free position.
+// Candidates are taken from the free positions only instead of scanning
+// every base like xCheck does. Holes left far below the highest used
+// position rarely fit a state with several arcs, and trying all of them for
+// every state makes the build quadratic, so such a state tries at most
+// maxTries of them before searching the last searchWindow positions. A hole
+// that failed maxTries states is not tried again, but stays free for the
+// other arcs.
+func (b *dartBuilder[V]) findBase(codes []int) int {
+	lo := max(2, codes[0]+1)
+	if len(codes) == 1 {
+		return nextIn(&b.free, lo) - codes[0]
+	}
+
+	pos := nextIn(&b.holes, lo)
+	for i := 0; i < maxTries && pos < b.top-searchWindow; i++ {
+		if b.fits(pos, codes) {
+			return pos - codes[0]
+		}
+		next := nextIn(&b.holes, pos+1)
+		b.tries = EnsureIndex(b.tries, pos)
+		if b.tries[pos] += 1; b.tries[pos] == maxTries {
+			b.holes[pos] = next
+		}
+		pos = next
+	}
+
+	pos = nextIn(&b.free, max(lo, b.top-searchWindow))
+	for !b.fits(pos, codes) {
+		pos = nextIn(&b.free, pos+1)
+	}
+	return pos - codes[0]
+}
@@ -0,0 +1,180 @@
+package go_tries
+
+import (
+	mrand "math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// Returns keys in ascending order with their index as value
+func sortedKeyValues(keys map[string]bool) ([]string, []int) {
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	values := make([]int, len(sorted))
+	for i := range values {
+		values[i] = i
+	}
+	return sorted, values
+}
+
+func testBuildMatchesPut(t *testing.T, binary bool) {
+	r := mrand.New(mrand.NewSource(1))
+	keys, values := sortedKeyValues(randomKeys(r, 2000, binary))
+
+	built, err := BuildDoubleArray(keys, values)
+	if err != nil {
+		t.Fatalf("expected BuildDoubleArray to succeed, got %v", err)
+	}
+	put := NewDoubleArrayTrie[int]()
+	for i, key := range keys {
+		put.Put(key, values[i])
+	}
+
+	if built.Len() != put.Len() {
+		t.Fatalf("expected Len to be %v, got %v", put.Len(), built.Len())
+	}
+	if err := built.Validate(); err != nil {
+		t.Fatalf("expected a valid trie, got %v", err)
+	}
+
+	probes := append([]string{""}, keys...)
+	for key := range randomKeys(r, 2000, binary) {
+		probes = append(probes, key)
+	}
+	for _, key := range probes {
+		expected, expectedOk := put.Get(key)
+		value, ok := built.Get(key)
+		if value != expected || ok != expectedOk {
+			t.Fatalf("expected Get for %q to be (%v, %v), got (%v, %v)", key, expected, expectedOk, value, ok)
+		}
+	}
+
+	var got []string
+	built.WalkPrefix("", func(key string, value int) bool {
+		got = append(got, key)
+		return true
+	})
+	if !reflect.DeepEqual(got, keys) {
+		t.Fatalf("expected WalkPrefix to visit the sorted keys")
+	}
+}
+
+func TestBuildDoubleArrayBinaryKeys(t *testing.T) {
+	testBuildMatchesPut(t, true)
+}
+
+func TestBuildDoubleArrayUTF8Keys(t *testing.T) {
+	testBuildMatchesPut(t, false)
+}
+
+func TestBuildDoubleArrayPrefixKeys(t *testing.T) {
+	keys := []string{"", "a", "ab", "abc", "abd", "b"}
+	d, err := BuildDoubleArray(keys, []int{0, 1, 2, 3, 4, 5})
+	if err != nil {
+		t.Fatalf("expected BuildDoubleArray to succeed, got %v", err)
+	}
+
+	for i, key := range keys {
+		if value, ok := d.Get(key); value != i || ok != true {
+			t.Errorf("expected Get for %q to be (%v, %v), got (%v, %v)", key, i, true, value, ok)
+		}
+	}
+	if _, ok := d.Get("abcd"); ok {
+		t.Errorf("expected Get for %q to be %v, got %v", "abcd", false, ok)
+	}
+}
+
+func TestBuildDoubleArrayThenModify(t *testing.T) {
+	d, err := BuildDoubleArray([]string{"bar", "baz", "foo"}, []int{1, 2, 3})
+	if err != nil {
+		t.Fatalf("expected BuildDoubleArray to succeed, got %v", err)
+	}
+
+	d.Put("ba", 4)
+	d.Put("food", 5)
+	d.Delete("baz")
+
+	expected := map[string]int{"ba": 4, "bar": 1, "foo": 3, "food": 5}
+	if d.Len() != len(expected) {
+		t.Errorf("expected Len to be %v, got %v", len(expected), d.Len())
+	}
+	for key, value := range expected {
+		if got, ok := d.Get(key); got != value || ok != true {
+			t.Errorf("expected Get for %q to be (%v, %v), got (%v, %v)", key, value, true, got, ok)
+		}
+	}
+	if _, ok := d.Get("baz"); ok {
+		t.Errorf("expected Get for %q to be %v, got %v", "baz", false, ok)
+	}
+	if err := d.Validate(); err != nil {
+		t.Errorf("expected a valid trie, got %v", err)
+	}
+}
+
+func TestBuildDoubleArrayEmpty(t *testing.T) {
+	d, err := BuildDoubleArray[int](nil, nil)
+	if err != nil {
+		t.Fatalf("expected BuildDoubleArray to succeed, got %v", err)
+	}
+	if d.Len() != 0 {
+		t.Errorf("expected Len to be %v, got %v", 0, d.Len())
+	}
+	d.Put("a", 1)
+	if value, _ := d.Get("a"); value != 1 {
+		t.Errorf("expected Get for %q to be %v, got %v", "a", 1, value)
+	}
+}
+
+func TestBuildDoubleArrayInvalidInput(t *testing.T) {
+	cases := []struct {
+		name   string
+		keys   []string
+		values []int
+	}{
+		{"unsorted", []string{"b", "a"}, []int{1, 2}},
+		{"duplicate", []string{"a", "a"}, []int{1, 2}},
+		{"terminator", []string{"a\x00b"}, []int{1}},
+		{"length mismatch", []string{"a", "b"}, []int{1}},
+	}
+
+	for _, c := range cases {
+		if d, err := BuildDoubleArray(c.keys, c.values); err == nil || d != nil {
+			t.Errorf("%s: expected BuildDoubleArray to fail, got %v", c.name, err)
+		}
+	}
+}
+
+func BenchmarkBuildDoubleArray(b *testing.B) {
+	keys, values := sortedKeyValues(randomKeys(mrand.New(mrand.NewSource(1)), 10000, false))
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		BuildDoubleArray(keys, values)
+	}
+}
+
+func BenchmarkBuildDoubleArrayMillionKeys(b *testing.B) {
+	keys, values := sortedKeyValues(randomKeys(mrand.New(mrand.NewSource(1)), 1000000, false))
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		BuildDoubleArray(keys, values)
+	}
+}
+
+func BenchmarkDoubleArrayTriePutSorted(b *testing.B) {
+	keys, values := sortedKeyValues(randomKeys(mrand.New(mrand.NewSource(1)), 10000, false))
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d := NewDoubleArrayTrie[int]()
+		for j, key := range keys {
+			d.Put(key, values[j])
+		}
+	}
+}
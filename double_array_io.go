@@ -0,0 +1,492 @@
+package go_tries
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+	"math/bits"
+)
+
+// Serialized DoubleArrayTrie. All integers are little endian.
+//
+//	magic    [4]byte  "GTDA"
+//	version  uint16
+//	flags    uint16   reserved, zero
+//	size     uint64   number of keys
+//	cells    uint64   length of the base and check arrays
+//	tailLen  uint64   length of the tail in bytes
+//	tails    uint64   number of tail segments
+//	base     [cells]int64
+//	check    [cells]int64
+//	segments [tails]struct{ offset, length uint32 }
+//	leaves   [(cells+63)/64]uint64  bitmap of the leaves, bit p-1 for position p
+//	ranks    [(cells+63)/64]uint64  number of leaves before every word of leaves
+//	offsets  [size+1]uint64   offset of the value of every leaf in values, in
+//	                          position order, followed by the length of values
+//	tail     [tailLen]byte
+//	values   [...]byte  the value of every leaf encoded on its own
+//	checksum uint32   CRC-32 (IEEE) of everything before it
+//
+// A leaf with base -r reads the length bytes of the tail from offset of
+// segment r, counting from 1, and its value from the offsets of its rank
+// among the leaves, so a mapped file decodes only the values it reads. The
+// header is 40 bytes so the arrays are 8 byte aligned. Version 1 ended the
+// segments of the tail with 0x00 instead and version 2 encoded the values
+// together without the leaves, ranks and offsets.
+const (
+	daMagic      = "GTDA"
+	daVersion    = 3
+	daHeaderSize = 40
+	// Number of integers read or written at once
+	daChunk = 4096
+)
+
+// Counts and checksums the bytes written through it
+type crcWriter struct {
+	w   io.Writer
+	crc hash.Hash32
+	n   int64
+}
+
+func (cw *crcWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.crc.Write(p[:n])
+	cw.n += int64(n)
+	return n, err
+}
+
+// Counts and checksums the bytes read through it
+type crcReader struct {
+	r   io.Reader
+	crc hash.Hash32
+	n   int64
+}
+
+func (cr *crcReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.crc.Write(p[:n])
+	cr.n += int64(n)
+	return n, err
+}
+
+// Reads exactly len(p) bytes, reporting an early end as ErrTruncated
+func readFull(r io.Reader, p []byte) error {
+	_, err := io.ReadFull(r, p)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrTruncated
+	}
+	return err
+}
+
+// Reads n bytes. The buffer grows as data arrives so a corrupt length
+// cannot allocate more than the input holds.
+func readBytes(r io.Reader, n uint64) ([]byte, error) {
+	if n > math.MaxInt64 {
+		return nil, fmt.Errorf("%w: length %d", ErrCorrupt, n)
+	}
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, int64(n)); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, ErrTruncated
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Reads n tail records in chunks
+func readTails(r io.Reader, n uint64) ([]tailRecord, error) {
+	if n > uint64(maxPosition) {
+		return nil, fmt.Errorf("%w: %d tail segments", ErrCorrupt, n)
+	}
+	var records []tailRecord
+	chunk := make([]byte, 8*daChunk)
+	for left := int(n); left > 0; {
+		size := min(left, daChunk)
+		if err := readFull(r, chunk[:8*size]); err != nil {
+			return nil, err
+		}
+		for i := 0; i < size; i++ {
+			records = append(records, tailRecord{
+				offset: binary.LittleEndian.Uint32(chunk[8*i:]),
+				length: binary.LittleEndian.Uint32(chunk[8*i+4:]),
+			})
+		}
+		left -= size
+	}
+	return records, nil
+}
+
+// Reads n little endian int64s in chunks
+func readInts(r io.Reader, n uint64) ([]int, error) {
+	if n > uint64(maxPosition) {
+		return nil, fmt.Errorf("%w: %d cells", ErrCorrupt, n)
+	}
+	var ints []int
+	chunk := make([]byte, 8*daChunk)
+	for left := int(n); left > 0; {
+		size := min(left, daChunk)
+		if err := readFull(r, chunk[:8*size]); err != nil {
+			return nil, err
+		}
+		for i := 0; i < size; i++ {
+			ints = append(ints, int(int64(binary.LittleEndian.Uint64(chunk[8*i:]))))
+		}
+		left -= size
+	}
+	return ints, nil
+}
+
+// SetValueCodec sets the codec WriteTo and ReadFrom use for values. The
+// default is GobCodec.
+func (d *DoubleArrayTrie[V]) SetValueCodec(codec ValueCodec[V]) {
+	d.codec = codec
+}
+
+func (d *DoubleArrayTrie[V]) valueCodec() ValueCodec[V] {
+	if d.codec == nil {
+		return GobCodec[V]{}
+	}
+	return d.codec
+}
+
+// Reports whether pos is a leaf, which holds a value
+func (d *DoubleArrayTrie[V]) isLeaf(pos int) bool {
+	return d.getCheck(pos) > 0 && d.getBase(pos) < 0
+}
+
+// Returns the bitmap of leaves over cells positions together with the
+// number of leaves before every word of it
+func leafRanks(leaves []int, cells int) ([]uint64, []uint64) {
+	bitmap := make([]uint64, (cells+63)/64)
+	ranks := make([]uint64, len(bitmap))
+	for _, pos := range leaves {
+		bitmap[(pos-1)/64] |= 1 << ((pos - 1) % 64)
+	}
+	count := 0
+	for w, word := range bitmap {
+		ranks[w] = uint64(count)
+		count += bits.OnesCount64(word)
+	}
+	return bitmap, ranks
+}
+
+// WriteTo writes the trie to w in a versioned binary format that ReadFrom
+// loads. Values are encoded one by one with the codec set by SetValueCodec,
+// with its SingleValueCodec methods if it has them. It returns the number
+// of bytes written.
+func (d *DoubleArrayTrie[V]) WriteTo(w io.Writer) (int64, error) {
+	cells := max(len(d.base), len(d.check))
+
+	var leaves []int
+	var values bytes.Buffer
+	offsets := []uint64{0}
+	for pos := 1; pos <= cells; pos++ {
+		if !d.isLeaf(pos) {
+			continue
+		}
+		leaves = append(leaves, pos)
+		if err := encodeValue(d.valueCodec(), &values, d.getValue(pos)); err != nil {
+			return 0, fmt.Errorf("go_tries: encoding values: %w", err)
+		}
+		offsets = append(offsets, uint64(values.Len()))
+	}
+	bitmap, ranks := leafRanks(leaves, cells)
+
+	cw := &crcWriter{w: w, crc: crc32.NewIEEE()}
+	buf := make([]byte, 0, 8*daChunk)
+	// Appends v to buf, writing buf out first when it is full
+	put := func(v uint64) error {
+		if len(buf) == cap(buf) {
+			if _, err := cw.Write(buf); err != nil {
+				return err
+			}
+			buf = buf[:0]
+		}
+		buf = binary.LittleEndian.AppendUint64(buf, v)
+		return nil
+	}
+
+	buf = append(buf, daMagic...)
+	buf = binary.LittleEndian.AppendUint16(buf, daVersion)
+	buf = binary.LittleEndian.AppendUint16(buf, 0)
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(d.size))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(cells))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(d.tail)))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(d.tails)))
+
+	for _, get := range []func(int) int{d.getBase, d.getCheck} {
+		for pos := 1; pos <= cells; pos++ {
+			if err := put(uint64(get(pos))); err != nil {
+				return cw.n, err
+			}
+		}
+	}
+	for _, record := range d.tails {
+		if err := put(uint64(record.offset) | uint64(record.length)<<32); err != nil {
+			return cw.n, err
+		}
+	}
+	for _, section := range [][]uint64{bitmap, ranks, offsets} {
+		for _, v := range section {
+			if err := put(v); err != nil {
+				return cw.n, err
+			}
+		}
+	}
+	if _, err := cw.Write(buf); err != nil {
+		return cw.n, err
+	}
+
+	if _, err := cw.Write(d.tail); err != nil {
+		return cw.n, err
+	}
+	if _, err := cw.Write(values.Bytes()); err != nil {
+		return cw.n, err
+	}
+
+	buf = binary.LittleEndian.AppendUint32(buf[:0], cw.crc.Sum32())
+	n, err := w.Write(buf)
+	return cw.n + int64(n), err
+}
+
+// ReadFrom replaces the contents of the trie with one written by WriteTo.
+// Input that ends early, fails its checksum or does not describe a valid
+// trie is rejected with an error wrapping ErrCorrupt and leaves the trie
+// unchanged. It returns the number of bytes read.
+func (d *DoubleArrayTrie[V]) ReadFrom(r io.Reader) (int64, error) {
+	cr := &crcReader{r: r, crc: crc32.NewIEEE()}
+
+	header := make([]byte, daHeaderSize)
+	if err := readFull(cr, header); err != nil {
+		return cr.n, err
+	}
+	if string(header[:4]) != daMagic {
+		return cr.n, ErrBadMagic
+	}
+	if version := binary.LittleEndian.Uint16(header[4:]); version != daVersion {
+		return cr.n, fmt.Errorf("%w %d", ErrVersion, version)
+	}
+	size := binary.LittleEndian.Uint64(header[8:])
+	cells := binary.LittleEndian.Uint64(header[16:])
+	tailLen := binary.LittleEndian.Uint64(header[24:])
+	tailCount := binary.LittleEndian.Uint64(header[32:])
+
+	base, err := readInts(cr, cells)
+	if err != nil {
+		return cr.n, err
+	}
+	check, err := readInts(cr, cells)
+	if err != nil {
+		return cr.n, err
+	}
+	tails, err := readTails(cr, tailCount)
+	if err != nil {
+		return cr.n, err
+	}
+	if size > cells {
+		return cr.n, fmt.Errorf("%w: %d keys in %d cells", ErrCorrupt, size, cells)
+	}
+	bitmap, err := readInts(cr, (cells+63)/64)
+	if err != nil {
+		return cr.n, err
+	}
+	ranks, err := readInts(cr, (cells+63)/64)
+	if err != nil {
+		return cr.n, err
+	}
+	offsets, err := readInts(cr, size+1)
+	if err != nil {
+		return cr.n, err
+	}
+	for i, offset := range offsets {
+		if offset < 0 || i == 0 && offset != 0 || i > 0 && offset < offsets[i-1] {
+			return cr.n, fmt.Errorf("%w: value offset %d", ErrCorrupt, offset)
+		}
+	}
+	tail, err := readBytes(cr, tailLen)
+	if err != nil {
+		return cr.n, err
+	}
+	values, err := readBytes(cr, uint64(offsets[size]))
+	if err != nil {
+		return cr.n, err
+	}
+
+	sum := cr.crc.Sum32()
+	trailer := make([]byte, 4)
+	if err := readFull(cr, trailer); err != nil {
+		return cr.n, err
+	}
+	if binary.LittleEndian.Uint32(trailer) != sum {
+		return cr.n, ErrChecksum
+	}
+
+	loaded := &DoubleArrayTrie[V]{
+		base:  base,
+		check: check,
+		tail:  tail,
+		tails: tails,
+		size:  int(min(size, math.MaxInt32)),
+		codec: d.codec,
+	}
+	if uint64(loaded.size) != size {
+		return cr.n, fmt.Errorf("%w: %d keys", ErrCorrupt, size)
+	}
+	leaves, err := loaded.validate()
+	if err != nil {
+		return cr.n, err
+	}
+
+	wantBitmap, wantRanks := leafRanks(leaves, len(base))
+	for w := range wantBitmap {
+		if uint64(bitmap[w]) != wantBitmap[w] || uint64(ranks[w]) != wantRanks[w] {
+			return cr.n, fmt.Errorf("%w: leaf index does not match the leaves", ErrCorrupt)
+		}
+	}
+
+	loaded.values = make([]V, len(base))
+	used := make([]bool, len(tails)+1)
+	for i, pos := range leaves {
+		value, err := decodeValue(loaded.valueCodec(), bytes.NewReader(values[offsets[i]:offsets[i+1]]))
+		if err != nil {
+			return cr.n, fmt.Errorf("%w: decoding value of leaf %d: %v", ErrCorrupt, pos, err)
+		}
+		loaded.values[pos-1] = value
+		used[-loaded.getBase(pos)] = true
+	}
+	// Segments of keys deleted before writing are reused
+	for pos := len(tails); pos >= 1; pos-- {
+		if !used[pos] {
+			loaded.freeTails = append(loaded.freeTails, pos)
+		}
+	}
+
+	*d = *loaded
+	return cr.n, nil
+}
+
+// Validate checks the internal consistency of the trie: every check names
+// a live inner state whose base leads to it, every leaf reads a tail
+// segment of its own that lies within the tail, every state is reachable
+// from the root,
+// every inner state but the root has arcs and Len agrees with the number
+// of leaves. It returns an error wrapping ErrCorrupt describing the
+// first problem found.
+func (d *DoubleArrayTrie[V]) Validate() error {
+	_, err := d.validate()
+	return err
+}
+
+// Validates the trie as Validate does. Returns the leaves in position
+// order.
+func (d *DoubleArrayTrie[V]) validate() ([]int, error) {
+	leaves, err := d.checkLayout()
+	if err != nil {
+		return nil, err
+	}
+
+	cells := len(d.check)
+	arcs := make([]int32, cells+1)
+	for pos := 2; pos <= cells; pos++ {
+		arcs[d.getCheck(pos)] += 1
+	}
+
+	// Follow the parents of every state up to a state known to be
+	// reachable. Coming back to a state of the same path is a cycle cut off
+	// from the root.
+	const (
+		unknown = iota
+		reachable
+		visiting
+	)
+	marks := make([]int8, cells+1)
+	marks[1] = reachable
+	var path []int
+	for pos := 2; pos <= cells; pos++ {
+		if d.getCheck(pos) == 0 {
+			continue
+		}
+		if d.getBase(pos) >= 0 && arcs[pos] == 0 {
+			return nil, fmt.Errorf("%w: state %d has no arcs", ErrCorrupt, pos)
+		}
+
+		path = path[:0]
+		for p := pos; marks[p] != reachable; p = d.getCheck(p) {
+			if marks[p] == visiting {
+				return nil, fmt.Errorf("%w: state %d is not reachable from the root", ErrCorrupt, pos)
+			}
+			marks[p] = visiting
+			path = append(path, p)
+		}
+		for _, p := range path {
+			marks[p] = reachable
+		}
+	}
+
+	if len(leaves) != d.size {
+		return nil, fmt.Errorf("%w: %d keys found for a length of %d", ErrCorrupt, len(leaves), d.size)
+	}
+	return leaves, nil
+}
+
+// Checks that every state hangs off its parent's base and that every leaf
+// reads a segment of the tail no other leaf reads. Returns the leaves in
+// position order.
+func (d *DoubleArrayTrie[V]) checkLayout() ([]int, error) {
+	cells := len(d.base)
+	if cells == 0 || d.getCheck(1) != 0 || d.getBase(1) < 1 {
+		return nil, fmt.Errorf("%w: bad root", ErrCorrupt)
+	}
+	for i, record := range d.tails {
+		if uint64(record.offset)+uint64(record.length) > uint64(len(d.tail)) {
+			return nil, fmt.Errorf("%w: tail segment %d ends past the tail", ErrCorrupt, i+1)
+		}
+	}
+	used := make([]bool, len(d.tails)+1)
+
+	var leaves []int
+	for pos := 2; pos <= cells; pos++ {
+		s, b := d.getCheck(pos), d.getBase(pos)
+		if s == 0 {
+			if b != 0 {
+				return nil, fmt.Errorf("%w: free position %d has base %d", ErrCorrupt, pos, b)
+			}
+			continue
+		}
+
+		if s < 0 || s > cells {
+			return nil, fmt.Errorf("%w: position %d has check %d", ErrCorrupt, pos, s)
+		}
+		if s != 1 && d.getCheck(s) == 0 {
+			return nil, fmt.Errorf("%w: position %d hangs off free position %d", ErrCorrupt, pos, s)
+		}
+		if code := pos - d.getBase(s); d.getBase(s) < 1 || code < terminator || code > maxCode {
+			return nil, fmt.Errorf("%w: position %d is not an arc of %d", ErrCorrupt, pos, s)
+		}
+
+		if b < 0 {
+			if -b < 1 || -b > len(d.tails) {
+				return nil, fmt.Errorf("%w: leaf %d has tail segment %d", ErrCorrupt, pos, -b)
+			}
+			if used[-b] {
+				return nil, fmt.Errorf("%w: leaf %d shares tail segment %d", ErrCorrupt, pos, -b)
+			}
+			used[-b] = true
+			leaves = append(leaves, pos)
+		}
+	}
+
+	for _, r := range d.freeTails {
+		if r < 1 || r > len(d.tails) || used[r] {
+			return nil, fmt.Errorf("%w: free tail segment %d", ErrCorrupt, r)
+		}
+		used[r] = true
+	}
+	return leaves, nil
+}
@@ -0,0 +1,298 @@
+package go_tries
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	mrand "math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Returns the keys and values of d in order
+func collect[V any](d *DoubleArrayTrie[V]) ([]string, []V) {
+	var keys []string
+	var values []V
+	d.WalkPrefix("", func(key string, value V) bool {
+		keys = append(keys, key)
+		values = append(values, value)
+		return true
+	})
+	return keys, values
+}
+
+func TestWriteToReadFrom(t *testing.T) {
+	r := mrand.New(mrand.NewSource(1))
+	d := NewDoubleArrayTrie[int]()
+	i := 0
+	for key := range randomKeys(r, 2000, true) {
+		d.Put(key, i)
+		i++
+	}
+	// Leave some freed positions behind
+	for key := range randomKeys(r, 200, true) {
+		d.Delete(key)
+	}
+
+	var buf bytes.Buffer
+	written, err := d.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("expected WriteTo to succeed, got %v", err)
+	}
+	if written != int64(buf.Len()) {
+		t.Errorf("expected WriteTo to report %v bytes, got %v", buf.Len(), written)
+	}
+
+	loaded := NewDoubleArrayTrie[int]()
+	read, err := loaded.ReadFrom(&buf)
+	if err != nil {
+		t.Fatalf("expected ReadFrom to succeed, got %v", err)
+	}
+	if read != written {
+		t.Errorf("expected ReadFrom to report %v bytes, got %v", written, read)
+	}
+
+	if loaded.Len() != d.Len() {
+		t.Errorf("expected Len to be %v, got %v", d.Len(), loaded.Len())
+	}
+	if err := loaded.Validate(); err != nil {
+		t.Errorf("expected a valid trie, got %v", err)
+	}
+	expectedKeys, expectedValues := collect(d)
+	keys, values := collect(loaded)
+	if !reflect.DeepEqual(keys, expectedKeys) || !reflect.DeepEqual(values, expectedValues) {
+		t.Fatalf("expected the loaded trie to hold the written keys and values")
+	}
+
+	// The loaded trie can still be modified
+	loaded.Put("a new key", -1)
+	if value, ok := loaded.Get("a new key"); value != -1 || ok != true {
+		t.Errorf("expected Get for %q to be (%v, %v), got (%v, %v)", "a new key", -1, true, value, ok)
+	}
+}
+
+func TestWriteToReadFromEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := NewDoubleArrayTrie[string]().WriteTo(&buf); err != nil {
+		t.Fatalf("expected WriteTo to succeed, got %v", err)
+	}
+
+	loaded := NewDoubleArrayTrie[string]()
+	loaded.Put("stale", "x")
+	if _, err := loaded.ReadFrom(&buf); err != nil {
+		t.Fatalf("expected ReadFrom to succeed, got %v", err)
+	}
+	if loaded.Len() != 0 {
+		t.Errorf("expected Len to be %v, got %v", 0, loaded.Len())
+	}
+	if _, ok := loaded.Get("stale"); ok {
+		t.Errorf("expected Get for %q to be %v, got %v", "stale", false, ok)
+	}
+}
+
+func serialized(t *testing.T) []byte {
+	d, err := BuildDoubleArray([]string{"", "bachelor", "badge", "jar", "jargon"}, []string{"e", "b", "d", "j", "g"})
+	if err != nil {
+		t.Fatalf("expected BuildDoubleArray to succeed, got %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := d.WriteTo(&buf); err != nil {
+		t.Fatalf("expected WriteTo to succeed, got %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadFromTruncated(t *testing.T) {
+	data := serialized(t)
+	for n := 0; n < len(data); n++ {
+		d := NewDoubleArrayTrie[string]()
+		d.Put("kept", "k")
+		if _, err := d.ReadFrom(bytes.NewReader(data[:n])); !errors.Is(err, ErrTruncated) {
+			t.Fatalf("expected ReadFrom of %v bytes to fail with %v, got %v", n, ErrTruncated, err)
+		}
+		if value, _ := d.Get("kept"); value != "k" {
+			t.Fatalf("expected a failed ReadFrom to leave the trie unchanged")
+		}
+	}
+}
+
+func TestReadFromCorrupt(t *testing.T) {
+	data := serialized(t)
+	for i := range data {
+		corrupt := append([]byte(nil), data...)
+		corrupt[i] ^= 0x40
+		_, err := NewDoubleArrayTrie[string]().ReadFrom(bytes.NewReader(corrupt))
+		if !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("expected ReadFrom with byte %v flipped to fail with %v, got %v", i, ErrCorrupt, err)
+		}
+	}
+}
+
+// Returns the first n free positions of d
+func freePositions[V any](d *DoubleArrayTrie[V], n int) []int {
+	var free []int
+	for pos := 2; len(free) < n; pos++ {
+		if d.getCheck(pos) == 0 {
+			free = append(free, pos)
+		}
+	}
+	return free
+}
+
+func TestValidate(t *testing.T) {
+	cases := map[string]func(d *DoubleArrayTrie[int]){
+		"BadRoot": func(d *DoubleArrayTrie[int]) {
+			d.setCheck(1, 2)
+		},
+		"FreeParent": func(d *DoubleArrayTrie[int]) {
+			leaf := d.getBase(1) + 'j'
+			d.setCheck(leaf, freePositions(d, 1)[0])
+		},
+		"NotAnArc": func(d *DoubleArrayTrie[int]) {
+			leaf := d.getBase(1) + 'j'
+			d.setBase(1, leaf+1)
+		},
+		"TailOutOfRange": func(d *DoubleArrayTrie[int]) {
+			d.setBase(d.getBase(1)+'j', -(len(d.tails) + 1))
+		},
+		"SegmentPastTail": func(d *DoubleArrayTrie[int]) {
+			d.tails[0].length = uint32(len(d.tail)) + 1
+		},
+		"SharedSegment": func(d *DoubleArrayTrie[int]) {
+			jar := d.getBase(1) + 'j'
+			for pos := 2; pos <= len(d.check); pos++ {
+				if pos != jar && d.isLeaf(pos) {
+					d.setBase(jar, d.getBase(pos))
+					return
+				}
+			}
+		},
+		"StateWithoutArcs": func(d *DoubleArrayTrie[int]) {
+			d.setBase(d.getBase(1)+'j', len(d.check)+maxCode)
+		},
+		"Unreachable": func(d *DoubleArrayTrie[int]) {
+			// Two states hanging off each other
+			free := freePositions(d, 2)
+			d.setBase(free[0], free[1]-1)
+			d.setCheck(free[0], free[1])
+			d.setBase(free[1], free[0]-1)
+			d.setCheck(free[1], free[0])
+		},
+		"Len": func(d *DoubleArrayTrie[int]) {
+			d.size += 1
+		},
+	}
+
+	for name, corrupt := range cases {
+		d := NewDoubleArrayTrie[int]()
+		d.Put("bachelor", 1)
+		d.Put("badge", 2)
+		d.Put("jar", 3)
+		if err := d.Validate(); err != nil {
+			t.Fatalf("%s: expected a valid trie, got %v", name, err)
+		}
+
+		corrupt(d)
+		if err := d.Validate(); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("%s: expected Validate to fail with %v, got %v", name, ErrCorrupt, err)
+		}
+
+		// The checksum of a written trie does not catch it either
+		var buf bytes.Buffer
+		if _, err := d.WriteTo(&buf); err != nil {
+			t.Fatalf("%s: expected WriteTo to succeed, got %v", name, err)
+		}
+		if _, err := NewDoubleArrayTrie[int]().ReadFrom(&buf); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("%s: expected ReadFrom to fail with %v, got %v", name, ErrCorrupt, err)
+		}
+	}
+}
+
+func TestReadFromHeader(t *testing.T) {
+	data := serialized(t)
+
+	badMagic := append([]byte(nil), data...)
+	copy(badMagic, "GTDB")
+	if _, err := NewDoubleArrayTrie[string]().ReadFrom(bytes.NewReader(badMagic)); !errors.Is(err, ErrBadMagic) {
+		t.Errorf("expected ReadFrom to fail with %v, got %v", ErrBadMagic, err)
+	}
+
+	badVersion := append([]byte(nil), data...)
+	badVersion[4] = daVersion + 1
+	if _, err := NewDoubleArrayTrie[string]().ReadFrom(bytes.NewReader(badVersion)); !errors.Is(err, ErrVersion) {
+		t.Errorf("expected ReadFrom to fail with %v, got %v", ErrVersion, err)
+	}
+}
+
+// Encodes strings one per line
+type lineCodec struct{}
+
+func (lineCodec) EncodeValues(w io.Writer, values []string) error {
+	for _, value := range values {
+		if _, err := fmt.Fprintln(w, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (lineCodec) DecodeValues(r io.Reader, n int) ([]string, error) {
+	var values []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		values = append(values, scanner.Text())
+	}
+	if len(values) != n {
+		return nil, fmt.Errorf("decoded %d values, expected %d", len(values), n)
+	}
+	return values, scanner.Err()
+}
+
+func TestWriteToValueCodec(t *testing.T) {
+	d := NewDoubleArrayTrie[string]()
+	d.SetValueCodec(lineCodec{})
+	d.Put("bachelor", "b")
+	d.Put("jar", "j")
+
+	var buf bytes.Buffer
+	if _, err := d.WriteTo(&buf); err != nil {
+		t.Fatalf("expected WriteTo to succeed, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "b\nj\n") {
+		t.Errorf("expected the values to be encoded by the codec")
+	}
+
+	loaded := NewDoubleArrayTrie[string]()
+	loaded.SetValueCodec(lineCodec{})
+	if _, err := loaded.ReadFrom(&buf); err != nil {
+		t.Fatalf("expected ReadFrom to succeed, got %v", err)
+	}
+	if value, _ := loaded.Get("jar"); value != "j" {
+		t.Errorf("expected Get for %q to be %q, got %q", "jar", "j", value)
+	}
+}
+
+func BenchmarkDoubleArrayTrieWriteTo(b *testing.B) {
+	keys, values := sortedKeyValues(randomKeys(mrand.New(mrand.NewSource(1)), 10000, false))
+	d, _ := BuildDoubleArray(keys, values)
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d.WriteTo(io.Discard)
+	}
+}
+
+func BenchmarkDoubleArrayTrieReadFrom(b *testing.B) {
+	keys, values := sortedKeyValues(randomKeys(mrand.New(mrand.NewSource(1)), 10000, false))
+	d, _ := BuildDoubleArray(keys, values)
+	var buf bytes.Buffer
+	d.WriteTo(&buf)
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewDoubleArrayTrie[int]().ReadFrom(bytes.NewReader(buf.Bytes()))
+	}
+}
@@ -0,0 +1,397 @@
+package go_tries
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math/bits"
+	"strings"
+)
+
+// Little endian int64 array stored in place in a byte slice
+type int64View []byte
+
+func (v int64View) len() int {
+	return len(v) / 8
+}
+
+// Returns the integer at pos, which counts from 1 like the positions of
+// the trie, or 0 when pos is out of range
+func (v int64View) at(pos int) int {
+	if pos < 1 || pos > v.len() {
+		return 0
+	}
+	return int(int64(binary.LittleEndian.Uint64(v[8*(pos-1):])))
+}
+
+// Little endian uint64 array stored in place in a byte slice
+type uint64View []byte
+
+// Returns the integer at i, which counts from 0, or 0 when i is out of
+// range
+func (v uint64View) at(i int) uint64 {
+	if i < 0 || i >= len(v)/8 {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(v[8*i:])
+}
+
+// MappedDoubleArray is a read-only DoubleArrayTrie served from a file
+// written by DoubleArrayTrie.WriteTo. The arrays, the tail and the values
+// are read in place from the mapped file, so opening it neither copies nor
+// parses them and processes opening the same file share its pages. A value
+// is decoded every time a query returns it. Opening only checks that the
+// header describes the file; a value that fails to decode makes Get report
+// its key as missing and the walks skip it, and Verify reports the damage.
+type MappedDoubleArray[V any] struct {
+	// Mapped file and the function releasing it
+	data  []byte
+	unmap func() error
+	// Views of the arrays, the tail segments and the tail in data
+	base     int64View
+	check    int64View
+	segments []byte
+	tail     []byte
+	// Leaf positions as a bitmap together with the number of leaves before
+	// every word of it, mapping a leaf to its rank, and the offsets of the
+	// values of the leaves by rank
+	leaves  uint64View
+	ranks   uint64View
+	offsets uint64View
+	values  []byte
+	codec   ValueCodec[V]
+	// Number of keys stored
+	size int
+}
+
+// OpenMappedDoubleArray maps the file at path, which was written by
+// DoubleArrayTrie.WriteTo, and decodes its values with codec, or with
+// GobCodec when codec is nil. Files that are too short or whose header
+// does not describe them are rejected with an error wrapping ErrCorrupt.
+// Open does not check the checksum since that reads the whole file; call
+// Verify for files that may be damaged.
+func OpenMappedDoubleArray[V any](path string, codec ValueCodec[V]) (*MappedDoubleArray[V], error) {
+	data, unmap, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := newMappedDoubleArray(data, codec)
+	if err != nil {
+		unmap()
+		return nil, err
+	}
+	m.unmap = unmap
+	return m, nil
+}
+
+func newMappedDoubleArray[V any](data []byte, codec ValueCodec[V]) (*MappedDoubleArray[V], error) {
+	if codec == nil {
+		codec = GobCodec[V]{}
+	}
+
+	if len(data) < daHeaderSize+4 {
+		return nil, ErrTruncated
+	}
+	if string(data[:4]) != daMagic {
+		return nil, ErrBadMagic
+	}
+	if version := binary.LittleEndian.Uint16(data[4:]); version != daVersion {
+		return nil, fmt.Errorf("%w %d", ErrVersion, version)
+	}
+	size := binary.LittleEndian.Uint64(data[8:])
+	cells := binary.LittleEndian.Uint64(data[16:])
+	tailLen := binary.LittleEndian.Uint64(data[24:])
+	tails := binary.LittleEndian.Uint64(data[32:])
+
+	if size > cells {
+		return nil, fmt.Errorf("%w: %d keys in %d cells", ErrCorrupt, size, cells)
+	}
+
+	// Every section has to fit in what is left of the file
+	rest := uint64(len(data) - daHeaderSize - 4)
+	start, truncated := daHeaderSize, false
+	section := func(n, width uint64) []byte {
+		if truncated || n > rest/width {
+			truncated = true
+			return nil
+		}
+		rest -= n * width
+		start += int(n * width)
+		return data[start-int(n*width) : start]
+	}
+	base, check, segments := section(cells, 8), section(cells, 8), section(tails, 8)
+	words := (uint64(len(base))/8 + 63) / 64
+	m := &MappedDoubleArray[V]{
+		data:     data,
+		base:     int64View(base),
+		check:    int64View(check),
+		segments: segments,
+		leaves:   uint64View(section(words, 8)),
+		ranks:    uint64View(section(words, 8)),
+		offsets:  uint64View(section(size+1, 8)),
+		tail:     section(tailLen, 1),
+		codec:    codec,
+		size:     int(size),
+	}
+	if truncated {
+		return nil, ErrTruncated
+	}
+	if valueLen := m.offsets.at(int(size)); valueLen != rest {
+		return nil, fmt.Errorf("%w: %d bytes of values", ErrCorrupt, valueLen)
+	}
+	m.values = data[start : len(data)-4]
+
+	if m.base.len() == 0 || m.check.at(1) != 0 || m.base.at(1) < 1 {
+		return nil, fmt.Errorf("%w: bad root", ErrCorrupt)
+	}
+	return m, nil
+}
+
+// Close unmaps the file. The trie must not be used afterwards.
+func (m *MappedDoubleArray[V]) Close() error {
+	if m.unmap == nil {
+		return nil
+	}
+	unmap := m.unmap
+	*m = MappedDoubleArray[V]{}
+	return unmap()
+}
+
+// Verify checks the file against its checksum.
+func (m *MappedDoubleArray[V]) Verify() error {
+	end := len(m.data) - 4
+	if crc32.ChecksumIEEE(m.data[:end]) != binary.LittleEndian.Uint32(m.data[end:]) {
+		return ErrChecksum
+	}
+	return nil
+}
+
+// Len returns the number of keys stored in the trie.
+func (m *MappedDoubleArray[V]) Len() int {
+	return m.size
+}
+
+// Returns the value of the leaf pos, decoded from the file
+func (m *MappedDoubleArray[V]) getValue(pos int) (V, error) {
+	var zero V
+	w, b := (pos-1)/64, uint((pos-1)%64)
+	word := m.leaves.at(w)
+	rank := m.ranks.at(w) + uint64(bits.OnesCount64(word&(1<<b-1)))
+	if word&(1<<b) == 0 || rank >= uint64(m.size) {
+		return zero, fmt.Errorf("%w: leaf %d has no value", ErrCorrupt, pos)
+	}
+
+	start, end := m.offsets.at(int(rank)), m.offsets.at(int(rank)+1)
+	if start > end || end > uint64(len(m.values)) {
+		return zero, fmt.Errorf("%w: value of leaf %d lies outside the values", ErrCorrupt, pos)
+	}
+	value, err := decodeValue(m.codec, bytes.NewReader(m.values[start:end]))
+	if err != nil {
+		return zero, fmt.Errorf("%w: decoding value of leaf %d: %v", ErrCorrupt, pos, err)
+	}
+	return value, nil
+}
+
+// Returns tail segment pos without copying it, or nil when the segment
+// does not lie within the tail
+func (m *MappedDoubleArray[V]) readTail(pos int) []byte {
+	if pos < 1 || pos > len(m.segments)/8 {
+		return nil
+	}
+	record := m.segments[8*(pos-1):]
+	offset := uint64(binary.LittleEndian.Uint32(record))
+	end := offset + uint64(binary.LittleEndian.Uint32(record[4:]))
+	if end > uint64(len(m.tail)) {
+		return nil
+	}
+	return m.tail[offset:end]
+}
+
+// Returns the codes of the arcs leaving s in order
+func (m *MappedDoubleArray[V]) arcs(s int) []int {
+	var result []int
+	b := m.base.at(s)
+	if b <= 0 {
+		return result
+	}
+	for ch := terminator; ch <= maxCode; ch += 1 {
+		if m.check.at(b+ch) == s {
+			result = append(result, ch)
+		}
+	}
+	return result
+}
+
+// Returns the leaf holding key or -1 if key is not stored
+func (m *MappedDoubleArray[V]) findLeaf(key string) int {
+	if !validKey(key) {
+		return -1
+	}
+
+	s := 1
+	for idx := 0; idx <= len(key); idx += 1 {
+		t := m.base.at(s) + codeAt(key, idx)
+		if m.check.at(t) != s {
+			return -1
+		}
+		if m.base.at(t) < 0 {
+			if string(m.readTail(-m.base.at(t))) != restAt(key, idx) {
+				return -1
+			}
+			return t
+		}
+		s = t
+	}
+	return -1
+}
+
+// Get returns the value stored at the given key and whether the key was
+// found.
+func (m *MappedDoubleArray[V]) Get(key string) (V, bool) {
+	var zero V
+	pos := m.findLeaf(key)
+	if pos == -1 {
+		return zero, false
+	}
+	value, err := m.getValue(pos)
+	return value, err == nil
+}
+
+// Lookup returns the value stored at the given key, ErrInvalidKey if the
+// key cannot be stored, ErrKeyNotFound if it is not or an error wrapping
+// ErrCorrupt if its value does not decode.
+func (m *MappedDoubleArray[V]) Lookup(key string) (V, error) {
+	var zero V
+	if !validKey(key) {
+		return zero, fmt.Errorf("%w: %q holds the terminator byte", ErrInvalidKey, key)
+	}
+	pos := m.findLeaf(key)
+	if pos == -1 {
+		return zero, notFound(key)
+	}
+	return m.getValue(pos)
+}
+
+// WalkPrefix calls fn for every key that starts with prefix, until fn
+// returns false. Keys are visited in ascending byte order.
+func (m *MappedDoubleArray[V]) WalkPrefix(prefix string, fn func(key string, value V) bool) {
+	if !validKey(prefix) {
+		return
+	}
+
+	s := 1
+	for idx := 0; idx < len(prefix); idx += 1 {
+		t := m.base.at(s) + ValueFromChar(int(prefix[idx]))
+		if m.check.at(t) != s {
+			return
+		}
+
+		// The only key below t continues in the tail
+		if m.base.at(t) < 0 {
+			key := prefix[:idx+1] + string(m.readTail(-m.base.at(t)))
+			if value, err := m.getValue(t); err == nil && strings.HasPrefix(key, prefix) {
+				fn(key, value)
+			}
+			return
+		}
+
+		s = t
+	}
+
+	m.walk(s, []byte(prefix), fn)
+}
+
+// Visits the keys below state s whose path spells buf. Returns false if fn
+// stopped the walk.
+func (m *MappedDoubleArray[V]) walk(s int, buf []byte, fn func(key string, value V) bool) bool {
+	for _, ch := range m.arcs(s) {
+		t := m.base.at(s) + ch
+		path := buf
+		if ch != terminator {
+			path = append(path, byte(ValueToChar(ch)))
+		}
+
+		if m.base.at(t) < 0 {
+			value, err := m.getValue(t)
+			if err != nil {
+				continue
+			}
+			key := append(path, m.readTail(-m.base.at(t))...)
+			if !fn(string(key), value) {
+				return false
+			}
+			continue
+		}
+
+		if !m.walk(t, path, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// CommonPrefixSearch returns every stored key that is a prefix of input,
+// shortest first.
+func (m *MappedDoubleArray[V]) CommonPrefixSearch(input string) []Match[V] {
+	var result []Match[V]
+	m.CommonPrefixSearchFunc(input, func(length int, value V) bool {
+		result = append(result, Match[V]{Key: input[:length], Length: length, Value: value})
+		return true
+	})
+	return result
+}
+
+// CommonPrefixSearchFunc calls fn with the length and value of every stored
+// key that is a prefix of input, shortest first, until fn returns false.
+func (m *MappedDoubleArray[V]) CommonPrefixSearchFunc(input string, fn func(length int, value V) bool) {
+	s := 1
+	for idx := 0; ; idx += 1 {
+		// A key ends at s when s has a terminator arc
+		t := m.base.at(s) + terminator
+		if m.check.at(t) == s {
+			if value, err := m.getValue(t); err == nil && !fn(idx, value) {
+				return
+			}
+		}
+
+		if idx >= len(input) || input[idx] == terminator {
+			return
+		}
+
+		t = m.base.at(s) + ValueFromChar(int(input[idx]))
+		if m.check.at(t) != s {
+			return
+		}
+
+		// The key below t matches if its tail is a prefix of the rest
+		if m.base.at(t) < 0 {
+			tail := m.readTail(-m.base.at(t))
+			end := idx + 1 + len(tail)
+			if end <= len(input) && input[idx+1:end] == string(tail) {
+				if value, err := m.getValue(t); err == nil {
+					fn(end, value)
+				}
+			}
+			return
+		}
+
+		s = t
+	}
+}
+
+// LongestPrefix returns the longest stored key that is a prefix of key,
+// together with its value.
+func (m *MappedDoubleArray[V]) LongestPrefix(key string) (string, V, bool) {
+	var value V
+	length := -1
+	m.CommonPrefixSearchFunc(key, func(l int, v V) bool {
+		length, value = l, v
+		return true
+	})
+	if length == -1 {
+		return "", value, false
+	}
+	return key[:length], value, true
+}
@@ -0,0 +1,196 @@
+package go_tries
+
+import (
+	"errors"
+	mrand "math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Writes d to a file in a temporary directory and returns its path
+func writeTrieFile[V any](t testing.TB, d *DoubleArrayTrie[V]) string {
+	path := filepath.Join(t.TempDir(), "trie.da")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := d.WriteTo(f); err != nil {
+		t.Fatalf("expected WriteTo to succeed, got %v", err)
+	}
+	return path
+}
+
+func TestMappedDoubleArray(t *testing.T) {
+	r := mrand.New(mrand.NewSource(1))
+	keys, values := sortedKeyValues(randomKeys(r, 2000, false))
+	d, err := BuildDoubleArray(keys, values)
+	if err != nil {
+		t.Fatalf("expected BuildDoubleArray to succeed, got %v", err)
+	}
+
+	m, err := OpenMappedDoubleArray[int](writeTrieFile(t, d), nil)
+	if err != nil {
+		t.Fatalf("expected OpenMappedDoubleArray to succeed, got %v", err)
+	}
+	defer m.Close()
+
+	if err := m.Verify(); err != nil {
+		t.Errorf("expected Verify to succeed, got %v", err)
+	}
+	if m.Len() != d.Len() {
+		t.Errorf("expected Len to be %v, got %v", d.Len(), m.Len())
+	}
+
+	probes := append([]string{""}, keys...)
+	for key := range randomKeys(r, 2000, false) {
+		probes = append(probes, key)
+	}
+	for _, key := range probes {
+		expected, expectedOk := d.Get(key)
+		if value, ok := m.Get(key); value != expected || ok != expectedOk {
+			t.Fatalf("expected Get for %q to be (%v, %v), got (%v, %v)", key, expected, expectedOk, value, ok)
+		}
+		if expected, got := d.CommonPrefixSearch(key+"suffix"), m.CommonPrefixSearch(key+"suffix"); !reflect.DeepEqual(got, expected) {
+			t.Fatalf("expected CommonPrefixSearch for %q to be %v, got %v", key+"suffix", expected, got)
+		}
+	}
+
+	for _, prefix := range []string{"", keys[100], keys[100][:1]} {
+		var expected, got []string
+		d.WalkPrefix(prefix, func(key string, value int) bool {
+			expected = append(expected, key)
+			return true
+		})
+		m.WalkPrefix(prefix, func(key string, value int) bool {
+			got = append(got, key)
+			return true
+		})
+		if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("expected WalkPrefix for %q to visit %v, got %v", prefix, expected, got)
+		}
+	}
+}
+
+func TestMappedDoubleArrayLongestPrefix(t *testing.T) {
+	d := NewDoubleArrayTrie[int]()
+	d.Put("a", 1)
+	d.Put("abc", 2)
+	d.Put("abcdef", 3)
+
+	m, err := OpenMappedDoubleArray[int](writeTrieFile(t, d), nil)
+	if err != nil {
+		t.Fatalf("expected OpenMappedDoubleArray to succeed, got %v", err)
+	}
+	defer m.Close()
+
+	if key, value, ok := m.LongestPrefix("abcde"); key != "abc" || value != 2 || ok != true {
+		t.Errorf("expected LongestPrefix to be (%q, %v, %v), got (%q, %v, %v)", "abc", 2, true, key, value, ok)
+	}
+	if _, _, ok := m.LongestPrefix("b"); ok {
+		t.Errorf("expected LongestPrefix for %q to be %v, got %v", "b", false, ok)
+	}
+}
+
+func TestMappedDoubleArrayDecodesOnGet(t *testing.T) {
+	d := NewDoubleArrayTrie[string]()
+	d.SetValueCodec(lineCodec{})
+	d.Put("bachelor", "b")
+	// Encodes to two lines, which lineCodec cannot decode as one value
+	d.Put("jar", "j\nk")
+
+	m, err := OpenMappedDoubleArray[string](writeTrieFile(t, d), lineCodec{})
+	if err != nil {
+		t.Fatalf("expected OpenMappedDoubleArray to succeed, got %v", err)
+	}
+	defer m.Close()
+
+	if value, ok := m.Get("bachelor"); value != "b" || ok != true {
+		t.Errorf("expected Get for %q to be (%q, %v), got (%q, %v)", "bachelor", "b", true, value, ok)
+	}
+	if _, ok := m.Get("jar"); ok != false {
+		t.Errorf("expected Get for %q to be %v, got %v", "jar", false, ok)
+	}
+	if _, err := m.Lookup("jar"); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected Lookup for %q to fail with %v, got %v", "jar", ErrCorrupt, err)
+	}
+
+	var keys []string
+	m.WalkPrefix("", func(key string, value string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if !reflect.DeepEqual(keys, []string{"bachelor"}) {
+		t.Errorf("expected WalkPrefix to visit %v, got %v", []string{"bachelor"}, keys)
+	}
+}
+
+func TestMappedDoubleArrayTruncated(t *testing.T) {
+	data := serialized(t)
+	for n := 0; n < len(data); n++ {
+		if _, err := newMappedDoubleArray[string](data[:n], nil); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("expected opening %v bytes to fail with %v, got %v", n, ErrCorrupt, err)
+		}
+	}
+}
+
+func TestMappedDoubleArrayCorrupt(t *testing.T) {
+	data := serialized(t)
+	for i := range data {
+		corrupt := append([]byte(nil), data...)
+		corrupt[i] ^= 0x40
+
+		m, err := newMappedDoubleArray[string](corrupt, nil)
+		if err != nil {
+			if !errors.Is(err, ErrCorrupt) {
+				t.Fatalf("expected opening with byte %v flipped to fail with %v, got %v", i, ErrCorrupt, err)
+			}
+			continue
+		}
+
+		// Damage the header does not reveal must not break queries
+		for _, key := range []string{"", "bachelor", "badge", "jar", "jargon", "jargons"} {
+			m.Get(key)
+			m.CommonPrefixSearch(key)
+		}
+		m.WalkPrefix("", func(key string, value string) bool { return true })
+
+		if err := m.Verify(); !errors.Is(err, ErrChecksum) {
+			t.Fatalf("expected Verify with byte %v flipped to fail with %v, got %v", i, ErrChecksum, err)
+		}
+	}
+}
+
+func TestOpenMappedDoubleArrayMissing(t *testing.T) {
+	if _, err := OpenMappedDoubleArray[int](filepath.Join(t.TempDir(), "missing"), nil); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected OpenMappedDoubleArray to fail with %v, got %v", os.ErrNotExist, err)
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty")
+	if err := os.WriteFile(empty, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenMappedDoubleArray[int](empty, nil); !errors.Is(err, ErrTruncated) {
+		t.Errorf("expected OpenMappedDoubleArray to fail with %v, got %v", ErrTruncated, err)
+	}
+}
+
+func BenchmarkMappedDoubleArrayGetStringKey(b *testing.B) {
+	d := NewDoubleArrayTrie[int]()
+	for i := 0; i < len(words); i++ {
+		d.Put(words[i], i)
+	}
+	m, err := OpenMappedDoubleArray[int](writeTrieFile(b, d), nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer m.Close()
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m.Get(words[i%len(words)])
+	}
+}
@@ -1,16 +1,22 @@
 package go_tries
 
 import (
+	"fmt"
+	"hash/maphash"
+	"math"
+	"math/bits"
+	"reflect"
+	"sort"
 	"strings"
-	"bytes"
 )
 
 const (
 	// Specifies an empty or available slot in the BC array
 	//emptyValue = 0
 	baseValue = 1
-	// Rune to use a boundary between words
-	boundary = "#"
+	// Byte terminating every key. It is used as the arc code of keys that
+	// end at an inner state, so it can never appear inside a key.
+	terminator = 0
 	// Minimum numerical code
 	minCode = 1
 	// Maximum numerical code
@@ -19,97 +25,316 @@ const (
 	growInc = 16
 )
 
-type DoubleArrayTrie struct {
+// Largest position of a state. ReadFrom does not load longer arrays.
+var maxPosition = math.MaxInt32
+
+// Largest length of the tail in bytes, which tail records address with 32
+// bits
+var maxTailLen uint64 = math.MaxUint32
+
+// A tail segment: the length bytes of the tail starting at offset
+type tailRecord struct {
+	offset uint32
+	length uint32
+}
+
+type DoubleArrayTrie[V any] struct {
 	// Base and check arrays
-	base []int
+	base  []int
 	check []int
-	// Tail array
-	tail string
-	// Current tail pos
-	tailPos int
+	// Values of the leaves, indexed like base and check
+	values []V
+	// Tail arena holding the rests of the keys below the leaves. Bytes are
+	// only ever appended, so segments can share them: identical rests are
+	// stored once and a rest may lie inside a longer one.
+	tail []byte
+	// Segments of the tail. A leaf with base -r reads segment r, counting
+	// from 1.
+	tails []tailRecord
+	// Segments no leaf reads, reused before tails grows
+	freeTails []int
+	// Offsets of the segments appended to the tail by a hash of their
+	// bytes, to find identical rests. It is rebuilt when nil.
+	tailIndex map[uint64]uint32
+	tailSeed  maphash.Seed
+	// Number of keys stored
+	size int
+	// Free list of the arrays, one bit per position, set for positions
+	// that can hold a new state. It covers the first freeLen positions and
+	// is rebuilt when the arrays are replaced.
+	free    []uint64
+	freeLen int
+	// Codec of the values when the trie is serialized
+	codec ValueCodec[V]
 }
 
 // Returns the current value of base
-func (d *DoubleArrayTrie) getBase(pos int) int {
+func (d *DoubleArrayTrie[V]) getBase(pos int) int {
 	idx := pos - 1
-	if idx >= cap(d.base) {
+	if idx < 0 || idx >= len(d.base) {
 		return 0
 	}
 	return d.base[idx]
 }
 
 // Returns the current value of check
-func (d *DoubleArrayTrie) getCheck(pos int) int {
+func (d *DoubleArrayTrie[V]) getCheck(pos int) int {
 	idx := pos - 1
-	if idx >= cap(d.check) {
+	if idx < 0 || idx >= len(d.check) {
 		return 0
 	}
 	return d.check[idx]
 }
 
-func (d *DoubleArrayTrie) setBase(pos int, node int) {
+func (d *DoubleArrayTrie[V]) setBase(pos int, node int) {
 	d.base = EnsureIndex(d.base, pos)
-	d.base[pos - 1] = node
+	d.base[pos-1] = node
 }
 
-func (d *DoubleArrayTrie) setCheck(pos int, node int) {
+func (d *DoubleArrayTrie[V]) setCheck(pos int, node int) {
+	tracked := d.free != nil && d.freeLen == len(d.check)
 	d.check = EnsureIndex(d.check, pos)
-	d.check[pos - 1] = node
+	d.check[pos-1] = node
+	if tracked {
+		// Positions added by the growth of the arrays are free
+		for p := d.freeLen + 1; p <= len(d.check); p++ {
+			d.markFree(p, p != pos)
+		}
+		d.freeLen = len(d.check)
+		d.markFree(pos, d.isFree(pos))
+	}
 }
 
-// Read tail starting at pos and ending in a boundary rune
-func (d *DoubleArrayTrie) ReadTail(pos int) string {
-	if pos < 1 {
-		panic("Unexpected position parameter for ReadTail")
+// Sets or clears the free list bit of pos
+func (d *DoubleArrayTrie[V]) markFree(pos int, free bool) {
+	d.free = EnsureIndex(d.free, pos/64)
+	if free {
+		d.free[pos/64] |= 1 << (pos % 64)
+	} else {
+		d.free[pos/64] &^= 1 << (pos % 64)
 	}
+}
 
-	if d.tail == "" {
-		return ""
+// Returns the first free position from pos on within the arrays or -1,
+// rebuilding the free list first if it does not cover them
+func (d *DoubleArrayTrie[V]) nextFree(pos int) int {
+	if d.free == nil || d.freeLen != len(d.check) {
+		d.free = make([]uint64, len(d.check)/64+1)
+		d.freeLen = len(d.check)
+		for p := 2; p <= len(d.check); p++ {
+			if d.isFree(p) {
+				d.markFree(p, true)
+			}
+		}
 	}
 
-	i := strings.Index(d.tail[pos-1:], boundary)
-	if i != -1 && pos != i {
-		return d.tail[pos-1:pos+i-1]
-	} else {
-		return ""
+	for i := pos / 64; i < len(d.free) && pos <= d.freeLen; i++ {
+		word := d.free[i]
+		if i == pos/64 {
+			word &^= 1<<(pos%64) - 1
+		}
+		if word != 0 {
+			if p := 64*i + bits.TrailingZeros64(word); p <= d.freeLen {
+				return p
+			}
+			return -1
+		}
 	}
+	return -1
 }
 
-// Write at tail a text string starting at pos
-func (d *DoubleArrayTrie) WriteTail(text string, pos int) {
-	if pos < 1 {
-		panic("Unexpected position parameter for WriteTail")
+// Returns the value stored at the leaf pos
+func (d *DoubleArrayTrie[V]) getValue(pos int) V {
+	idx := pos - 1
+	if idx < 0 || idx >= len(d.values) {
+		var zero V
+		return zero
 	}
+	return d.values[idx]
+}
 
-	// We were asked to just append the text to the end of tail
-	if len(d.tail) == 0 || d.tailPos == pos {
-		d.tail = d.tail + text
+func (d *DoubleArrayTrie[V]) setValue(pos int, value V) {
+	d.values = EnsureIndex(d.values, pos)
+	d.values[pos-1] = value
+}
+
+// Clears the state at pos, which returns it to the free list
+func (d *DoubleArrayTrie[V]) release(pos int) {
+	var zero V
+	d.setBase(pos, 0)
+	d.setCheck(pos, 0)
+	d.setValue(pos, zero)
+}
+
+// Reports whether pos can hold a new state. The root is never free.
+func (d *DoubleArrayTrie[V]) isFree(pos int) bool {
+	return pos > 1 && d.getCheck(pos) <= 0
+}
+
+// Returns the bytes of tail segment pos without copying them, or nil when
+// there is no such segment
+func (d *DoubleArrayTrie[V]) tailBytes(pos int) []byte {
+	if pos < 1 || pos > len(d.tails) {
+		return nil
+	}
+	record := d.tails[pos-1]
+	end := record.offset + record.length
+	return d.tail[record.offset:end:end]
+}
+
+// Read the tail segment pos. Positions outside the segments read as an
+// empty segment.
+func (d *DoubleArrayTrie[V]) ReadTail(pos int) string {
+	return string(d.tailBytes(pos))
+}
+
+// Write text as the tail segment pos, or as a new segment when pos is just
+// past the last one. The bytes of an identical segment are reused, or else
+// text is appended to the tail, so writing never copies the tail. It
+// returns ErrOutOfRange when pos is neither a segment nor just past the
+// last one and ErrCapacity when the tail would outgrow its largest length,
+// leaving the tail unchanged in both cases.
+func (d *DoubleArrayTrie[V]) WriteTail(text string, pos int) error {
+	if pos < 1 || pos > len(d.tails)+1 {
+		return fmt.Errorf("%w: writing segment %d of %d", ErrOutOfRange, pos, len(d.tails))
+	}
+	if uint64(len(d.tail))+uint64(len(text)) > maxTailLen {
+		return fmt.Errorf("%w: %d tail bytes in use", ErrCapacity, len(d.tail))
+	}
+
+	record := d.storeTail(text)
+	if pos == len(d.tails)+1 {
+		d.tails = append(d.tails, record)
 	} else {
-		var buffer bytes.Buffer
-		for i := pos; i< len(text); i+=1 {
-			buffer.WriteString(string(text[i]))
+		d.tails[pos-1] = record
+	}
+	return nil
+}
 
-			if string(text[i]) == boundary {
-				break
-			}
+// Returns a record of text, pointing at the bytes of an identical segment
+// when there is one or else at text appended to the tail
+func (d *DoubleArrayTrie[V]) storeTail(text string) tailRecord {
+	if text == "" {
+		return tailRecord{}
+	}
+	// Appends that later segments overwrote leave stale offsets behind,
+	// so the index is rebuilt once they outnumber the segments
+	if d.tailIndex == nil || len(d.tailIndex) > 2*len(d.tails) {
+		d.indexTail()
+	}
+
+	length := uint32(len(text))
+	h := maphash.String(d.tailSeed, text)
+	if offset, ok := d.tailIndex[h]; ok && uint64(offset)+uint64(length) <= uint64(len(d.tail)) &&
+		string(d.tail[offset:offset+length]) == text {
+		return tailRecord{offset: offset, length: length}
+	}
+
+	offset := uint32(len(d.tail))
+	d.tail = append(d.tail, text...)
+	d.tailIndex[h] = offset
+	return tailRecord{offset: offset, length: length}
+}
+
+// Indexes the segments of the tail by a hash of their bytes
+func (d *DoubleArrayTrie[V]) indexTail() {
+	d.tailSeed = maphash.MakeSeed()
+	d.tailIndex = make(map[uint64]uint32, len(d.tails))
+	for pos := range d.tails {
+		if segment := d.tailBytes(pos + 1); len(segment) > 0 {
+			d.tailIndex[maphash.Bytes(d.tailSeed, segment)] = d.tails[pos].offset
 		}
-		str := buffer.String()
+	}
+}
 
-		d.tail = d.tail[:pos-1] + str + d.tail[d.tailPos - len(str):]
+// Writes text as a new tail segment, reusing a segment no leaf reads when
+// there is one. Returns the position of the segment, or the error of
+// WriteTail.
+func (d *DoubleArrayTrie[V]) newTail(text string) (int, error) {
+	pos := len(d.tails) + 1
+	n := len(d.freeTails)
+	if n > 0 {
+		pos = d.freeTails[n-1]
 	}
+	if err := d.WriteTail(text, pos); err != nil {
+		return 0, err
+	}
+	if n > 0 {
+		d.freeTails = d.freeTails[:n-1]
+	}
+	return pos, nil
+}
+
+// Returns the tail segment pos to the segments newTail reuses
+func (d *DoubleArrayTrie[V]) freeTail(pos int) {
+	d.tails[pos-1] = tailRecord{}
+	d.freeTails = append(d.freeTails, pos)
+}
 
-	if len(d.tail) > d.tailPos {
-		d.tailPos = len(d.tail) + 1
+// Drops the first n bytes of tail segment pos. The rest keeps its bytes.
+func (d *DoubleArrayTrie[V]) cutTail(pos int, n int) {
+	record := &d.tails[pos-1]
+	n = min(n, int(record.length))
+	record.offset += uint32(n)
+	record.length -= uint32(n)
+}
+
+// Puts text in front of tail segment pos. When the bytes before the
+// segment already spell text, as they do for a rest that was cut off a
+// longer one, the segment grows over them. Otherwise the error of
+// WriteTail is returned.
+func (d *DoubleArrayTrie[V]) prependTail(pos int, text string) error {
+	record := d.tails[pos-1]
+	if n := uint32(len(text)); n <= record.offset && string(d.tail[record.offset-n:record.offset]) == text {
+		d.tails[pos-1] = tailRecord{offset: record.offset - n, length: record.length + n}
+		return nil
 	}
+	return d.WriteTail(text+d.ReadTail(pos), pos)
+}
+
+// Lays out segments in a new tail, storing a segment that ends another
+// inside it. Returns the tail and the records of the segments in order.
+func packTails(segments []string) ([]byte, []tailRecord) {
+	// Sorting the reversed segments puts every segment right before the
+	// segments it ends, if any
+	order := make([]int, len(segments))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := segments[order[i]], segments[order[j]]
+		for k := 1; k <= len(a) && k <= len(b); k++ {
+			if a[len(a)-k] != b[len(b)-k] {
+				return a[len(a)-k] < b[len(b)-k]
+			}
+		}
+		return len(a) < len(b)
+	})
+
+	var tail []byte
+	records := make([]tailRecord, len(segments))
+	for i := len(order) - 1; i >= 0; i-- {
+		segment := segments[order[i]]
+		length := uint32(len(segment))
+		if i+1 < len(order) {
+			next := segments[order[i+1]]
+			if strings.HasSuffix(next, segment) {
+				end := records[order[i+1]].offset + records[order[i+1]].length
+				records[order[i]] = tailRecord{offset: end - length, length: length}
+				continue
+			}
+		}
+		records[order[i]] = tailRecord{offset: uint32(len(tail)), length: length}
+		tail = append(tail, segment...)
+	}
+	return tail, records
 }
 
 // NewDoubleArrayTrie allocates and returns a new *DoubleArrayTrie.
-func NewDoubleArrayTrie() *DoubleArrayTrie {
-	d := &DoubleArrayTrie{
-		base:   make([]int, 10, 10),
-		check:   make([]int, 10, 10),
-		tail: "",
-		tailPos: 1,
+func NewDoubleArrayTrie[V any]() *DoubleArrayTrie[V] {
+	d := &DoubleArrayTrie[V]{
+		base:  make([]int, 10, 10),
+		check: make([]int, 10, 10),
 	}
 	// Set initial value of base at root
 	d.setBase(1, baseValue)
@@ -117,60 +342,241 @@ func NewDoubleArrayTrie() *DoubleArrayTrie {
 	return d
 }
 
-func (d *DoubleArrayTrie) Get(key string) bool {
+// Returns the arc code of key at idx. The position just past the end of
+// the key yields the terminator.
+func codeAt(key string, idx int) int {
+	if idx >= len(key) {
+		return terminator
+	}
+	return ValueFromChar(int(key[idx]))
+}
+
+// Returns the part of key that follows the arc taken at idx
+func restAt(key string, idx int) string {
+	if idx >= len(key) {
+		return ""
+	}
+	return key[idx+1:]
+}
+
+// Reports whether key can be stored in the trie
+func validKey(key string) bool {
+	return strings.IndexByte(key, terminator) == -1
+}
+
+// ValidKey reports whether key can be stored in the trie. Keys holding the
+// terminator byte 0x00 cannot.
+func (d *DoubleArrayTrie[V]) ValidKey(key string) bool {
+	return validKey(key)
+}
+
+// Len returns the number of keys stored in the trie.
+func (d *DoubleArrayTrie[V]) Len() int {
+	return d.size
+}
+
+// Get returns the value stored at the given key and whether the key was
+// found.
+func (d *DoubleArrayTrie[V]) Get(key string) (V, bool) {
+	if t := d.findLeaf(key); t != -1 {
+		return d.getValue(t), true
+	}
+	var zero V
+	return zero, false
+}
+
+// Returns the leaf holding key or -1 if key is not stored. A terminator
+// byte in key would follow the arc of the key ending there.
+func (d *DoubleArrayTrie[V]) findLeaf(key string) int {
+	if !validKey(key) {
+		return -1
+	}
 	idx, t := d.findTailPos(key)
 	if idx == -1 {
-		return false
+		return -1
 	}
 	// We still have to read the rest from the tail
 	// compare it with the rest of the string
-	if idx < len(key) {
-		restPos := -d.getBase(t)
-		rest := d.ReadTail(restPos)
+	if string(d.tailBytes(-d.getBase(t))) != restAt(key, idx) {
+		return -1
+	}
+	return t
+}
 
-		if rest == key[idx + 1:] {
-			return true
-		} else {
-			return false
-		}
+// Delete removes the given key. States left without arcs are removed and
+// a state left with a single leaf becomes that leaf, so the trie has the
+// shape it would have had if the key was never stored. Freed positions are
+// reused by Put. It returns the removed value and whether the key was
+// found.
+func (d *DoubleArrayTrie[V]) Delete(key string) (V, bool) {
+	var zero V
+	t := d.findLeaf(key)
+	if t == -1 {
+		return zero, false
 	}
 
-	// We reached the end of the string, check if we have compared all chars,
-	// otherwise fail as we still have chars left in tail
-	if idx == len(key) {
-		return true
-	} else {
-		return false
+	old := d.getValue(t)
+	s := d.getCheck(t)
+	d.freeTail(-d.getBase(t))
+	d.release(t)
+	d.size -= 1
+
+	// Remove ancestors left without arcs
+	for s != 1 && len(d.findArcs(s)) == 0 {
+		parent := d.getCheck(s)
+		d.release(s)
+		s = parent
 	}
+	d.fold(s)
+	return old, true
 }
 
-func (d *DoubleArrayTrie) Delete(key string) bool {
-	idx, t := d.findTailPos(key)
+// Turns the state s into a leaf when its only arc leads to a leaf, and
+// then its ancestors left with s as their only arc. The leaf keeps its tail
+// segment with the bytes of the removed arcs put in front of it. The states
+// are kept when the tail cannot take those bytes.
+func (d *DoubleArrayTrie[V]) fold(s int) {
+	if s == 1 {
+		return
+	}
+	arcs := d.findArcs(s)
+	if len(arcs) != 1 || d.getBase(d.getBase(s)+arcs[0]) >= 0 {
+		return
+	}
 
-	if idx == -1 {
-		return false
+	// Collect the bytes of the arcs to remove from the leaf up
+	leaf := d.getBase(s) + arcs[0]
+	var path []byte
+	if arcs[0] != terminator {
+		path = append(path, byte(ValueToChar(arcs[0])))
+	}
+	top := s
+	for parent := d.getCheck(top); parent != 1 && len(d.findArcs(parent)) == 1; parent = d.getCheck(top) {
+		path = append(path, byte(ValueToChar(top-d.getBase(parent))))
+		top = parent
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
 	}
 
-	// We still have to read the rest from the tail
-	// compare it with the rest of the string
-	if idx < len(key) {
-		rest := d.ReadTail(-d.getBase(t))
+	tailPos, value := -d.getBase(leaf), d.getValue(leaf)
+	if d.prependTail(tailPos, string(path)) != nil {
+		return
+	}
+	d.release(leaf)
+	for pos := s; pos != top; {
+		parent := d.getCheck(pos)
+		d.release(pos)
+		pos = parent
+	}
+	d.setBase(top, -tailPos)
+	d.setValue(top, value)
+}
 
-		if rest == key[idx + 1:] {
-			// Clear out base and check
-			d.setBase(t, 0)
-			d.setCheck(t, 0)
-			return true
-		} else {
-			return false
+// Compact rewrites the tail without the bytes of deleted keys, storing a
+// segment that ends another inside it, and trims free positions off the end
+// of the arrays, reallocating them to fit. It returns the number of bytes
+// reclaimed.
+func (d *DoubleArrayTrie[V]) Compact() int {
+	before := d.footprint()
+
+	cells := 1
+	for pos := len(d.check); pos > 1; pos-- {
+		if d.getCheck(pos) != 0 {
+			cells = pos
+			break
 		}
 	}
+	d.base = append([]int(nil), d.base[:cells]...)
+	d.check = append([]int(nil), d.check[:cells]...)
+	d.values = append([]V(nil), d.values[:min(cells, len(d.values))]...)
+
+	// Segments are renumbered in the order of their leaves
+	var segments []string
+	for pos := 2; pos <= cells; pos++ {
+		if d.isLeaf(pos) {
+			segments = append(segments, d.ReadTail(-d.getBase(pos)))
+			d.setBase(pos, -len(segments))
+		}
+	}
+	d.tail, d.tails = packTails(segments)
+	d.freeTails = nil
+	d.tailIndex = nil
 
-	return false
+	// The free list is rebuilt for the trimmed arrays when next needed
+	d.free = nil
+
+	return before - d.footprint()
+}
+
+// Returns the bytes allocated for the arrays, free lists, tail and tail
+// index. An index entry takes its hash, its offset and a control byte, in
+// a table at most 7/8 full.
+func (d *DoubleArrayTrie[V]) footprint() int {
+	intSize := bits.UintSize / 8
+	valueSize := int(reflect.TypeFor[V]().Size())
+	recordSize := int(reflect.TypeFor[tailRecord]().Size())
+	indexSize := (8 + 4 + 1) * len(d.tailIndex) * 8 / 7
+	return intSize*(cap(d.base)+cap(d.check)+cap(d.freeTails)) + 8*cap(d.free) + valueSize*cap(d.values) +
+		cap(d.tail) + recordSize*cap(d.tails) + indexSize
+}
+
+// Lookup returns the value stored at the given key, ErrInvalidKey if the
+// key cannot be stored or ErrKeyNotFound if it is not.
+func (d *DoubleArrayTrie[V]) Lookup(key string) (V, error) {
+	if !validKey(key) {
+		var zero V
+		return zero, fmt.Errorf("%w: %q holds the terminator byte", ErrInvalidKey, key)
+	}
+	value, ok := d.Get(key)
+	if !ok {
+		return value, notFound(key)
+	}
+	return value, nil
+}
+
+// Insert stores value at the given key. It returns ErrInvalidKey for keys
+// rejected by ValidKey and ErrCapacity when the arrays could outgrow
+// their largest position, and stores nothing in either case.
+func (d *DoubleArrayTrie[V]) Insert(key string, value V) error {
+	if !validKey(key) {
+		return fmt.Errorf("%w: %q holds the terminator byte", ErrInvalidKey, key)
+	}
+	if d.exceedsCapacity(key) {
+		return fmt.Errorf("%w: %d positions in use", ErrCapacity, len(d.check))
+	}
+	_, _, err := d.put(key, value)
+	return err
+}
+
+// Reports whether storing key could need positions past maxPosition or
+// tail bytes past maxTailLen. Put places at most one state per byte of key
+// plus two, and each lands within one base, and the growth of EnsureIndex,
+// past the end of the arrays. At most the key is appended to the tail.
+func (d *DoubleArrayTrie[V]) exceedsCapacity(key string) bool {
+	return max(len(d.base), len(d.check))+(len(key)+2)*(maxCode+growInc+2) > maxPosition ||
+		uint64(len(d.tail))+uint64(len(key)) > maxTailLen
+}
+
+// Put stores value at the given key. This method is similar to
+// findTailPos. It returns the previous value and whether it was replaced.
+// Keys rejected by ValidKey, or that would outgrow the arrays, are not
+// stored; use Insert to learn why.
+func (d *DoubleArrayTrie[V]) Put(key string, value V) (V, bool) {
+	if !validKey(key) || d.exceedsCapacity(key) {
+		var zero V
+		return zero, false
+	}
+	old, replaced, _ := d.put(key, value)
+	return old, replaced
 }
 
-// Add specified key into trie. This method is similar to findTailPos
-func (d *DoubleArrayTrie) Add(key string) bool {
+// Stores value at the given key, which the caller has checked against
+// ValidKey and exceedsCapacity. Returns the previous value, whether it was
+// replaced, and the error of a tail write, which that check rules out.
+func (d *DoubleArrayTrie[V]) put(key string, value V) (V, bool, error) {
+	var zero V
+
 	idx := -1
 	s := 1
 	var t int
@@ -178,19 +584,22 @@ func (d *DoubleArrayTrie) Add(key string) bool {
 	for {
 		idx += 1
 
-		ch := ValueFromChar(int(key[idx]))
+		ch := codeAt(key, idx)
 		t = d.getBase(s) + ch
 
 		// Case when check does not match with base. We have no match.
 		if d.getCheck(t) != s {
 			// Case when we have a conflict and we have to relocate the base
-			if d.getCheck(t) != 0 {
-				d.relocateBase(s, t, key, idx)
-			} else {
-				// Case 1. Empty string or without conflicts. Just insert at tail
-				d.separate(key, idx, s, d.tailPos)
-				}
-			return true
+			if !d.isFree(t) {
+				s = d.relocateBase(s, t, ch)
+			}
+			// Empty string or without conflicts. Just insert at tail
+			if err := d.separate(key, idx, s); err != nil {
+				return zero, false, err
+			}
+			d.setValue(d.getBase(s)+ch, value)
+			d.size += 1
+			return zero, false, nil
 		}
 
 		// Case when base denotes that the rest of the string
@@ -205,174 +614,148 @@ func (d *DoubleArrayTrie) Add(key string) bool {
 
 	// We still have to read the rest from the tail
 	// compare it with the rest of the string. If match is found then the key is already inserted
-	if idx < len(key) {
-		rest := d.ReadTail(-d.getBase(t))
-
-		if rest == key[idx+1:] {
-			return true
-		}
+	rest := restAt(key, idx)
+	if string(d.tailBytes(-d.getBase(t))) == rest {
+		old := d.getValue(t)
+		d.setValue(t, value)
+		return old, true, nil
 	}
 
-	// We reached the end of the string, check if we have compared all chars,
-	// otherwise attempt to insert at the end
-	if idx == len(key) {
-		return true
-	} else {
-		if d.getBase(t) != 0 {
-			d.tailInsert(t, key[idx + 1:])
-		}
+	leaf, err := d.tailInsert(t, rest)
+	if err != nil {
+		return zero, false, err
 	}
-
-	return false
-
+	d.setValue(leaf, value)
+	d.size += 1
+	return zero, false, nil
 }
 
-// Update base and check by separating the first char of slice
-func (d *DoubleArrayTrie) separate(slice string, idx int, s int, tailPos int) {
-	checkPos := d.getBase(s) + ValueFromChar(int(slice[idx]))
+// Update base and check by separating the char of slice at idx into a
+// leaf whose tail segment holds the rest of slice. Returns the error of
+// newTail, leaving the leaf unset.
+func (d *DoubleArrayTrie[V]) separate(slice string, idx int, s int) error {
+	checkPos := d.getBase(s) + codeAt(slice, idx)
 
+	tailPos, err := d.newTail(restAt(slice, idx))
+	if err != nil {
+		return err
+	}
 	d.setBase(checkPos, -tailPos)
 	d.setCheck(checkPos, s)
-	d.WriteTail(slice[idx + 1:] + boundary, tailPos)
+	return nil
 }
 
-// Update base to cater multiple conflicts
-func (d *DoubleArrayTrie) relocateBase(s int, t int, slice string, idx int) {
-	temp1 := s
-	var temp2 int
-	var oldCheck int
-	var list []int
+// Resolve the conflict at t, which state s needs for its arc ch but
+// another state already owns, by moving the arcs of whichever of the two
+// states has fewer of them to a new base. Returns the position of s, which
+// changes when s itself is one of the moved arcs.
+func (d *DoubleArrayTrie[V]) relocateBase(s int, t int, ch int) int {
+	other := d.getCheck(t)
 
-	// Find concecutive arcs for s and t nodes
 	list1 := d.findArcs(s)
-	list2 := d.findArcs(d.getCheck(t))
+	list2 := d.findArcs(other)
 
-	// Find bigger list and node. Small optimization.
-	if len(list1) + 1 < len(list2) {
-		oldCheck = s
-		list = list1
-	} else {
-		oldCheck = d.getCheck(t)
-		list = list2
+	// Relocate the node with the smaller list. Small optimization.
+	if other != 0 && len(list1)+1 >= len(list2) {
+		return d.moveArcs(other, list2, d.xCheck(list2), s)
 	}
 
-	// save old tail pos
-	oldTailPos := d.getBase(oldCheck)
-	d.setBase(oldCheck, d.xCheck(list))
-
-	i := -1
-
-	for {
-		i += 1
+	// The new arc has to fit next to the existing arcs of s
+	return d.moveArcs(s, list1, d.xCheck(append(list1, ch)), s)
+}
 
-		// We only do list.length iterations == number of max consecutive conflicts we need to resolve
-		if i > len(list) {
-			break
-		}
+// Move the arcs in list of state s so that they hang off newBase. Returns
+// the position of the state track after the move.
+func (d *DoubleArrayTrie[V]) moveArcs(s int, list []int, newBase int, track int) int {
+	oldBase := d.getBase(s)
 
+	for _, ch := range list {
 		// Calculate check and base and update them
-		temp1 = oldTailPos + list[i]
-		temp2 = d.getBase(oldCheck) + list[i]
+		temp1 := oldBase + ch
+		temp2 := newBase + ch
 
 		d.setBase(temp2, d.getBase(temp1))
-		d.setCheck(temp2, oldCheck)
+		d.setCheck(temp2, s)
+		d.setValue(temp2, d.getValue(temp1))
 
 		if d.getBase(temp1) > 0 {
-			// find min offset w from the check array and update them to point to the correct parents
-			w := 1
-
-			for {
-				if w >= len(d.check) {
-					break
-				}
-				ch := d.getBase(temp1) + w
-
-				// Update new check to point to the corect parent
-				if d.getCheck(ch) == temp1 {
-					d.setCheck(ch, temp2)
-				}
+			// Update the children of the moved state to point to the correct parent
+			for _, w := range d.findArcs(temp1) {
+				d.setCheck(d.getBase(temp1)+w, temp2)
 			}
 		}
-	}
 
-	// Negate old base and check
-	d.setBase(temp1, 0)
-	d.setCheck(temp2, 0)
+		// Free the old position
+		d.release(temp1)
+		if temp1 == track {
+			track = temp2
+		}
+	}
 
-	// Update new base and check
-	d.separate(slice, idx, temp2, d.tailPos)
+	d.setBase(s, newBase)
+	return track
 }
 
-
-// Insert key into tail starting at tailPos
-func (d *DoubleArrayTrie) tailInsert(s int, key string)  {
-	// Save old pos
+// Insert the rest of a key into the leaf s whose tail segment differs
+// from it. The common prefix of both becomes a chain of states and the
+// two remainders are stored as separate tail segments. Returns the
+// position of the new leaf, or the error of separate.
+func (d *DoubleArrayTrie[V]) tailInsert(s int, key string) (int, error) {
+	// Save old pos and value
 	oldTailPos := -d.getBase(s)
+	oldTail := d.ReadTail(oldTailPos)
+	oldValue := d.getValue(s)
+	var zero V
+	d.setValue(s, zero)
 
 	// Init variables
 	var list = []int{0, 0}
-	idx := 0
 	length := 0
 
-	// Find length of common chars in tail
-	for {
-		if length > len(key) {
-			break
-		}
-		// Find longest common prefix length between tail and key
-		if key[length] == d.tail[length] {
-			length += 1
-		} else {
-			break
-		}
+	// Find longest common prefix length between tail and key
+	for length < len(oldTail) && length < len(key) && oldTail[length] == key[length] {
+		length += 1
 	}
 
 	// Appends a sequence of arcs for the longest prefix
-	for {
-		// We have reached the end. Break now.
-		if idx >= length {
-			break
-		}
-		// For each different character
-		ch := d.tail[idx]
-
-		list[0] = ValueFromChar(int(ch))
+	for idx := 0; idx < length; idx += 1 {
+		list[0] = ValueFromChar(int(oldTail[idx]))
 		// find next available place for common conflict at ch
-		d.setBase(s, d.xCheck(list))
+		d.setBase(s, d.xCheck(list[:1]))
 		// Update check to point to base that was originated from
-		d.setCheck(d.getBase(s) + list[0], s)
+		d.setCheck(d.getBase(s)+list[0], s)
 
 		s = d.getBase(s) + list[0]
-		idx += 1
 	}
-	list[0] = ValueFromChar(int(d.tail[length]))
-	list[1] = ValueFromChar(int(key[length]))
+	list[0] = codeAt(oldTail, length)
+	list[1] = codeAt(key, length)
 	d.setBase(s, d.xCheck(list))
 
+	// The old remainder ends the old segment, which keeps its bytes and
+	// drops those now spelled by arcs
 	q := d.getBase(s) + list[0]
 	d.setBase(q, -oldTailPos)
 	d.setCheck(q, s)
-	d.WriteTail(d.tail[length:], oldTailPos)
+	d.setValue(q, oldValue)
+	d.cutTail(oldTailPos, length+1)
 
-	d.separate(key, length, s, d.tailPos)
+	if err := d.separate(key, length, s); err != nil {
+		return 0, err
+	}
+	return d.getBase(s) + list[1], nil
 }
 
 // Find max consecutive entries such as
 // CHECK(BASE(s) + i) == s
-func (d *DoubleArrayTrie) findArcs(s int) []int {
-	listLength := maxCode - minCode + 1
+func (d *DoubleArrayTrie[V]) findArcs(s int) []int {
 	var result []int
-	i := minCode - 1
-	var t int
+	if s == 0 || d.getBase(s) <= 0 {
+		return result
+	}
 
-	for {
-		if i > listLength {
-			break
-		}
-		i += 1
-		t = d.getBase(s) + i
-		c := d.getCheck(t)
-		if c == s {
+	for i := terminator; i <= maxCode; i += 1 {
+		t := d.getBase(s) + i
+		if d.getCheck(t) == s {
 			result = append(result, i)
 		}
 	}
@@ -380,45 +763,49 @@ func (d *DoubleArrayTrie) findArcs(s int) []int {
 	return result
 }
 
-// Find minimum available q number such as CHECK(basePos + list[c]) !== 0
-func (d *DoubleArrayTrie) xCheck(list []int) int {
-	basePos := 1
-
-	for {
-		found := false
-
-		for ch := 0; ch < len(list) ; ch += 1 {
-			checkPos := d.getCheck(basePos + list[ch])
-
-			if checkPos > 0 {
-				found = true
-				break
-			}
-		}
-
-		if !found {
-			break
+// Find minimum available q number such as CHECK(basePos + list[c]) == 0
+// for every c. Only bases putting the first arc on a position of the free
+// list, or past the end of the arrays, are tried.
+func (d *DoubleArrayTrie[V]) xCheck(list []int) int {
+	for pos := d.nextFree(1 + list[0]); pos != -1; pos = d.nextFree(pos + 1) {
+		if d.fits(pos-list[0], list) {
+			return pos - list[0]
 		}
+	}
 
+	basePos := max(1, len(d.check)+1-list[0])
+	for !d.fits(basePos, list) {
 		basePos += 1
 	}
-
 	return basePos
 }
 
+// Reports whether the positions of the arcs of list from basePos are all
+// free
+func (d *DoubleArrayTrie[V]) fits(basePos int, list []int) bool {
+	for _, ch := range list {
+		if !d.isFree(basePos + ch) {
+			return false
+		}
+	}
+	return true
+}
 
-func (d *DoubleArrayTrie) findTailPos(key string) (int, int) {
+// Walk the arcs of key and return the index of the key char that led to a
+// leaf together with the leaf position. Returns -1, -1 if there is no leaf
+// on the path of key.
+func (d *DoubleArrayTrie[V]) findTailPos(key string) (int, int) {
 	idx := -1
 	s := 1
 	var t int
 
 	for {
 		idx += 1
-		if idx >= len(key) {
-			break
+		if idx > len(key) {
+			return -1, -1
 		}
 
-		ch := ValueFromChar(int(key[idx]))
+		ch := codeAt(key, idx)
 		t = d.getBase(s) + ch
 
 		// Case when check does not match with base. We have no match.
@@ -437,4 +824,291 @@ func (d *DoubleArrayTrie) findTailPos(key string) (int, int) {
 	}
 
 	return idx, t
-}
\ No newline at end of file
+}
+
+// WalkPrefix calls fn for every key that starts with prefix, until fn
+// returns false. Keys are visited in ascending byte order, which follows
+// the code order of the arcs.
+func (d *DoubleArrayTrie[V]) WalkPrefix(prefix string, fn func(key string, value V) bool) {
+	if !validKey(prefix) {
+		return
+	}
+
+	s := 1
+	for idx := 0; idx < len(prefix); idx += 1 {
+		t := d.getBase(s) + ValueFromChar(int(prefix[idx]))
+		if d.getCheck(t) != s {
+			return
+		}
+
+		// The only key below t continues in the tail
+		if d.getBase(t) < 0 {
+			key := prefix[:idx+1] + string(d.tailBytes(-d.getBase(t)))
+			if strings.HasPrefix(key, prefix) {
+				fn(key, d.getValue(t))
+			}
+			return
+		}
+
+		s = t
+	}
+
+	d.walk(s, []byte(prefix), fn)
+}
+
+// Visits the keys below state s whose path spells buf. Returns false if fn
+// stopped the walk.
+func (d *DoubleArrayTrie[V]) walk(s int, buf []byte, fn func(key string, value V) bool) bool {
+	for _, ch := range d.findArcs(s) {
+		t := d.getBase(s) + ch
+		path := buf
+		if ch != terminator {
+			path = append(path, byte(ValueToChar(ch)))
+		}
+
+		if d.getBase(t) < 0 {
+			key := append(path, d.tailBytes(-d.getBase(t))...)
+			if !fn(string(key), d.getValue(t)) {
+				return false
+			}
+			continue
+		}
+
+		if !d.walk(t, path, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// A key found by CommonPrefixSearch. Key is the first Length bytes of the
+// searched input.
+type Match[V any] struct {
+	Key    string
+	Length int
+	Value  V
+}
+
+// CommonPrefixSearch returns every stored key that is a prefix of input,
+// shortest first.
+func (d *DoubleArrayTrie[V]) CommonPrefixSearch(input string) []Match[V] {
+	var result []Match[V]
+	d.CommonPrefixSearchFunc(input, func(length int, value V) bool {
+		result = append(result, Match[V]{Key: input[:length], Length: length, Value: value})
+		return true
+	})
+	return result
+}
+
+// CommonPrefixSearchFunc calls fn with the length and value of every stored
+// key that is a prefix of input, shortest first, until fn returns false.
+// It does not allocate.
+func (d *DoubleArrayTrie[V]) CommonPrefixSearchFunc(input string, fn func(length int, value V) bool) {
+	s := 1
+	for idx := 0; ; idx += 1 {
+		// A key ends at s when s has a terminator arc
+		t := d.getBase(s) + terminator
+		if d.getCheck(t) == s && !fn(idx, d.getValue(t)) {
+			return
+		}
+
+		if idx >= len(input) || input[idx] == terminator {
+			return
+		}
+
+		t = d.getBase(s) + ValueFromChar(int(input[idx]))
+		if d.getCheck(t) != s {
+			return
+		}
+
+		// The key below t matches if its tail is a prefix of the rest
+		if d.getBase(t) < 0 {
+			tail := d.tailBytes(-d.getBase(t))
+			end := idx + 1 + len(tail)
+			if end <= len(input) && input[idx+1:end] == string(tail) {
+				fn(idx+1+len(tail), d.getValue(t))
+			}
+			return
+		}
+
+		s = t
+	}
+}
+
+// LongestPrefix returns the longest stored key that is a prefix of key,
+// together with its value. It does not allocate.
+func (d *DoubleArrayTrie[V]) LongestPrefix(key string) (string, V, bool) {
+	var value V
+	length := -1
+	d.CommonPrefixSearchFunc(key, func(l int, v V) bool {
+		length, value = l, v
+		return true
+	})
+	if length == -1 {
+		return "", value, false
+	}
+	return key[:length], value, true
+}
+
+// Returns an iterator positioned before the first key. Keys are ordered as
+// in WalkPrefix.
+func (d *DoubleArrayTrie[V]) Iterator() *DoubleArrayTrieIterator[V] {
+	return &DoubleArrayTrieIterator[V]{
+		d:     d,
+		stack: []dartFrame{{s: 1, arcs: d.findArcs(1), i: -1}},
+	}
+}
+
+// Returns an iterator positioned before the first key that is not less
+// than key, so that Next moves to that key and Prev to the key before it.
+func (d *DoubleArrayTrie[V]) Seek(key string) *DoubleArrayTrieIterator[V] {
+	it := d.Iterator()
+	it.between = true
+	for idx := 0; ; idx += 1 {
+		top := &it.stack[len(it.stack)-1]
+		ch := codeAt(key, idx)
+		j := sort.SearchInts(top.arcs, ch)
+		if idx >= len(key) || j == len(top.arcs) || top.arcs[j] != ch {
+			top.i = j - 1
+			return it
+		}
+
+		t := d.getBase(top.s) + ch
+		if d.getBase(t) < 0 {
+			// Compare the key below t with the sought key
+			leaf := key[:idx]
+			if ch != terminator {
+				leaf = key[:idx+1] + string(d.tailBytes(-d.getBase(t)))
+			}
+			if leaf >= key {
+				top.i = j - 1
+			} else {
+				top.i = j
+			}
+			return it
+		}
+
+		top.i = j
+		it.stack = append(it.stack, dartFrame{s: t, arcs: d.findArcs(t), i: -1})
+	}
+}
+
+// A state on the path of an iterator, its arcs and the index into arcs of
+// the arc the path follows, -1 when the iterator is at the state.
+type dartFrame struct {
+	s    int
+	arcs []int
+	i    int
+}
+
+// DoubleArrayTrieIterator is a cursor over the keys of a DoubleArrayTrie.
+// It must not be used after the trie is modified.
+type DoubleArrayTrieIterator[V any] struct {
+	d     *DoubleArrayTrie[V]
+	stack []dartFrame
+	// Set after a Seek until the cursor moves
+	between bool
+}
+
+// Returns the leaf the iterator is at or -1
+func (it *DoubleArrayTrieIterator[V]) current() int {
+	if it.between || len(it.stack) == 0 {
+		return -1
+	}
+	top := it.stack[len(it.stack)-1]
+	if top.i != -1 || it.d.getBase(top.s) >= 0 {
+		return -1
+	}
+	return top.s
+}
+
+// Pushes the state of arc i of the top frame and reports whether it is a
+// leaf
+func (it *DoubleArrayTrieIterator[V]) push(i int) bool {
+	top := &it.stack[len(it.stack)-1]
+	top.i = i
+	t := it.d.getBase(top.s) + top.arcs[i]
+	it.stack = append(it.stack, dartFrame{s: t, arcs: it.d.findArcs(t), i: -1})
+	return it.d.getBase(t) < 0
+}
+
+// Next moves to the next key and reports whether there is one.
+func (it *DoubleArrayTrieIterator[V]) Next() bool {
+	it.between = false
+	for len(it.stack) > 0 {
+		top := &it.stack[len(it.stack)-1]
+		if top.i+1 < len(top.arcs) {
+			if it.push(top.i + 1) {
+				return true
+			}
+			continue
+		}
+		it.stack = it.stack[:len(it.stack)-1]
+	}
+	return false
+}
+
+// Prev moves to the previous key and reports whether there is one.
+func (it *DoubleArrayTrieIterator[V]) Prev() bool {
+	if len(it.stack) == 0 {
+		// Past the end, step back from behind the last arc of root
+		arcs := it.d.findArcs(1)
+		it.stack = append(it.stack, dartFrame{s: 1, arcs: arcs, i: len(arcs)})
+	} else if it.between {
+		// Seek left the path at the arc before the sought key
+		it.stack[len(it.stack)-1].i += 1
+	}
+	it.between = false
+
+	for {
+		top := &it.stack[len(it.stack)-1]
+		if top.i == -1 {
+			if len(it.stack) == 1 {
+				return false
+			}
+			it.stack = it.stack[:len(it.stack)-1]
+			continue
+		}
+
+		// Inner states hold no keys so only their arcs are visited.
+		// Descend to the last state below the previous arc.
+		if top.i == 0 {
+			top.i = -1
+			continue
+		}
+		leaf := it.push(top.i - 1)
+		for !leaf && len(it.stack[len(it.stack)-1].arcs) > 0 {
+			last := &it.stack[len(it.stack)-1]
+			leaf = it.push(len(last.arcs) - 1)
+		}
+		if leaf {
+			return true
+		}
+	}
+}
+
+// Key returns the key at the iterator or "" if it is not at a key.
+func (it *DoubleArrayTrieIterator[V]) Key() string {
+	t := it.current()
+	if t == -1 {
+		return ""
+	}
+
+	var key []byte
+	for _, frame := range it.stack[:len(it.stack)-1] {
+		if ch := frame.arcs[frame.i]; ch != terminator {
+			key = append(key, byte(ValueToChar(ch)))
+		}
+	}
+	return string(append(key, it.d.tailBytes(-it.d.getBase(t))...))
+}
+
+// Value returns the value at the iterator or the zero value if it is not
+// at a key.
+func (it *DoubleArrayTrieIterator[V]) Value() V {
+	if t := it.current(); t != -1 {
+		return it.d.getValue(t)
+	}
+	var zero V
+	return zero
+}
@@ -1,19 +1,24 @@
 package go_tries
 
 import (
+	mrand "math/rand"
+	"reflect"
+	"sort"
+	"strconv"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestInitTail(t *testing.T) {
-	d := NewDoubleArrayTrie()
+	d := NewDoubleArrayTrie[int]()
 
-	if d.tail != "" {
-		t.Errorf("expected tail initial value to be %v, got %v", "", d.tail)
+	if len(d.tail) != 0 || len(d.tails) != 0 {
+		t.Errorf("expected tail initial value to be %q with no segments, got %q with %v", "", d.tail, len(d.tails))
 	}
 }
 
 func TestInitBase(t *testing.T) {
-	d := NewDoubleArrayTrie()
+	d := NewDoubleArrayTrie[int]()
 
 	if d.getBase(1) != baseValue {
 		t.Errorf("expected tail initial value to be %v, got %v", baseValue, d.getBase(1))
@@ -21,7 +26,7 @@ func TestInitBase(t *testing.T) {
 }
 
 func TestGetSetBase(t *testing.T) {
-	d := NewDoubleArrayTrie()
+	d := NewDoubleArrayTrie[int]()
 
 	d.setBase(2, 5)
 
@@ -31,7 +36,7 @@ func TestGetSetBase(t *testing.T) {
 }
 
 func TestGetSetCheck(t *testing.T) {
-	d := NewDoubleArrayTrie()
+	d := NewDoubleArrayTrie[int]()
 
 	d.setCheck(2, 5)
 
@@ -41,7 +46,7 @@ func TestGetSetCheck(t *testing.T) {
 }
 
 func TestReadTailZeroIndex(t *testing.T) {
-	d := NewDoubleArrayTrie()
+	d := NewDoubleArrayTrie[int]()
 
 	if d.ReadTail(1) != "" {
 		t.Errorf("expected tail array value at 0 to be %v, got %v", "", d.ReadTail(1))
@@ -49,7 +54,7 @@ func TestReadTailZeroIndex(t *testing.T) {
 }
 
 func TestReadTailNonZeroIndex(t *testing.T) {
-	d := NewDoubleArrayTrie()
+	d := NewDoubleArrayTrie[int]()
 
 	if d.ReadTail(2) != "" {
 		t.Errorf("expected tail array value at 0 to be %v, got %v", "", d.ReadTail(1))
@@ -57,87 +62,171 @@ func TestReadTailNonZeroIndex(t *testing.T) {
 }
 
 func TestReadTailNonZeroTail(t *testing.T) {
-	d := NewDoubleArrayTrie()
+	d := NewDoubleArrayTrie[int]()
 
-	d.WriteTail("Hello#", 1)
+	d.WriteTail("Hello", 1)
 
 	if d.ReadTail(1) != "Hello" {
-		t.Errorf("expected tail array value at 0 to be %v, got %v", "Hello", d.ReadTail(1))
+		t.Errorf("expected tail segment 1 to be %v, got %v", "Hello", d.ReadTail(1))
 	}
 }
 
 func TestReadTailNonZeroTailMultiple(t *testing.T) {
-	d := NewDoubleArrayTrie()
+	d := NewDoubleArrayTrie[int]()
 
-	d.WriteTail("Hello#", 1)
-	d.WriteTail("World#", 7)
+	d.WriteTail("Hello", 1)
+	d.WriteTail("World", 2)
 
-	if d.ReadTail(7) != "World" {
-		t.Errorf("expected tail array value starting at 7 to be %v, got %v", "World", d.ReadTail(7))
+	if d.ReadTail(2) != "World" {
+		t.Errorf("expected tail segment 2 to be %v, got %v", "World", d.ReadTail(2))
 	}
 }
 
 func TestWriteTailInitial(t *testing.T) {
-	d := NewDoubleArrayTrie()
+	d := NewDoubleArrayTrie[int]()
 
-	d.WriteTail("hello#", d.tailPos)
+	d.WriteTail("hello", len(d.tails)+1)
 
-	if d.tail != "hello#" {
-		t.Errorf("expected tail array value to be %v, got %v", "hello#", d.tail)
+	if string(d.tail) != "hello" {
+		t.Errorf("expected tail array value to be %q, got %q", "hello", d.tail)
 	}
 }
 
 func TestWriteTailNoOverlapping(t *testing.T) {
-	d := NewDoubleArrayTrie()
+	d := NewDoubleArrayTrie[int]()
 
-	d.WriteTail("hello#", 1)
-	d.WriteTail("world#", 7)
+	d.WriteTail("hello", 1)
+	d.WriteTail("world", 2)
 
-	if d.tail != "hello#world#" {
-		t.Errorf("expected tail array value to be %v, got %v", "hello#world#", d.tail)
+	if string(d.tail) != "helloworld" {
+		t.Errorf("expected tail array value to be %q, got %q", "helloworld", d.tail)
 	}
 }
 
-func TestWriteTailOverlapping(t *testing.T) {
-	d := NewDoubleArrayTrie()
+func TestWriteTailShared(t *testing.T) {
+	d := NewDoubleArrayTrie[int]()
 
-	d.WriteTail("hello#", 1)
-	d.WriteTail("world#", 3)
+	d.WriteTail("hello", 1)
+	d.WriteTail("world", 2)
+	d.WriteTail("hello", 3)
 
-	if d.tail != "held#o#" {
-		t.Errorf("expected tail array value to be %v, got %v", "held#o#", d.tail)
+	if string(d.tail) != "helloworld" {
+		t.Errorf("expected tail array value to be %q, got %q", "helloworld", d.tail)
+	}
+	if d.tails[2] != d.tails[0] {
+		t.Errorf("expected segment 3 to share the bytes of segment 1, got %v and %v", d.tails[2], d.tails[0])
+	}
+}
+
+func TestWriteTailReplace(t *testing.T) {
+	d := NewDoubleArrayTrie[int]()
+
+	d.WriteTail("hello", 1)
+	d.WriteTail("ld", 1)
+
+	// Bytes are only appended, Compact drops those no segment reads
+	if string(d.tail) != "hellold" {
+		t.Errorf("expected tail array value to be %q, got %q", "hellold", d.tail)
+	}
+	if d.ReadTail(1) != "ld" {
+		t.Errorf("expected tail segment 1 to be %v, got %v", "ld", d.ReadTail(1))
+	}
+}
+
+func TestTailIndexBounded(t *testing.T) {
+	d := NewDoubleArrayTrie[int]()
+
+	// Every rewrite appends and indexes a new rest
+	for i := 0; i < 1000; i++ {
+		d.WriteTail(strconv.Itoa(i), 1)
+	}
+	if len(d.tailIndex) > 2*len(d.tails)+1 {
+		t.Errorf("expected the tail index to hold at most %v entries, got %v", 2*len(d.tails)+1, len(d.tailIndex))
+	}
+
+	// The index counts towards what Compact reclaims
+	withIndex := d.footprint()
+	d.tailIndex = nil
+	if d.footprint() >= withIndex {
+		t.Errorf("expected the footprint to count the tail index, got %v with and %v without", withIndex, d.footprint())
+	}
+}
+
+func TestTailSharing(t *testing.T) {
+	d := NewDoubleArrayTrie[int]()
+
+	// The rest of bachelor is cut off its segment in place
+	d.Put("bachelor", 1)
+	d.Put("badge", 2)
+	if string(d.tail) != "achelorge" {
+		t.Errorf("expected tail to be %q, got %q", "achelorge", d.tail)
+	}
+
+	// and grows back over the same bytes
+	d.Delete("badge")
+	if string(d.tail) != "achelorge" {
+		t.Errorf("expected tail to be %q, got %q", "achelorge", d.tail)
+	}
+	if d.ReadTail(-d.getBase(d.getBase(1)+'b')) != "achelor" {
+		t.Errorf("expected the leaf of %q to read %q", "bachelor", "achelor")
+	}
+
+	// Identical rests are stored once
+	d.Put("jar", 3)
+	d.Put("xar", 4)
+	if string(d.tail) != "achelorgear" {
+		t.Errorf("expected tail to be %q, got %q", "achelorgear", d.tail)
+	}
+	if err := d.Validate(); err != nil {
+		t.Errorf("expected a valid trie, got %v", err)
+	}
+}
+
+func TestPackTails(t *testing.T) {
+	segments := []string{"helor", "x", "bachelor", "", "lor", "x", "or"}
+	tail, records := packTails(segments)
+
+	// Every segment lies inside bachelor but x
+	if len(tail) != len("bachelor")+1 {
+		t.Errorf("expected tail of %v bytes, got %q", len("bachelor")+1, tail)
+	}
+	for i, segment := range segments {
+		record := records[i]
+		if got := string(tail[record.offset : record.offset+record.length]); got != segment {
+			t.Errorf("expected segment %v to be %q, got %q", i, segment, got)
+		}
 	}
 }
 
 func TestGetKeyExistsInTrie(t *testing.T) {
-	d := NewDoubleArrayTrie()
-	d.Add("baby")
+	d := NewDoubleArrayTrie[int]()
+	d.Put("baby", 1)
 
-	if d.Get("baby") != true {
-		t.Errorf("expected search for key %v to be %v, got %v", "baby", true, false)
+	if value, ok := d.Get("baby"); ok != true || value != 1 {
+		t.Errorf("expected search for key %v to be %v, got %v", "baby", 1, value)
 	}
 }
 
 func TestGetKeyDeleteInTrie(t *testing.T) {
-	d := NewDoubleArrayTrie()
-	d.Add("baby")
+	d := NewDoubleArrayTrie[int]()
+	d.Put("baby", 1)
 
-	if d.Delete("baby") != true {
+	if _, ok := d.Delete("baby"); ok != true {
 		t.Errorf("expected delete for key %v to be %v, got %v", "babe", true, false)
 	}
 
-	if d.Get("baby") != false {
+	if _, ok := d.Get("baby"); ok != false {
 		t.Errorf("expected search for key %v to be %v, got %v", "babe", false, true)
 	}
 }
 
 func TestFindArcsInTrieSimple(t *testing.T) {
-	d := NewDoubleArrayTrie()
+	d := NewDoubleArrayTrie[int]()
 	d.setCheck(3, 1)
 	d.setBase(3, 1)
 	d.setCheck(2, 3)
 	d.setBase(2, -1)
-	d.WriteTail("aby#", 1)
+	d.WriteTail("aby", 1)
 
 	if d.findArcs(3)[0] != 1 {
 		t.Errorf("expected findArcs for pos %v to be %v, got %v", 3, 1, d.findArcs(3)[0])
@@ -145,7 +234,7 @@ func TestFindArcsInTrieSimple(t *testing.T) {
 }
 
 func TestFindArcsInTrieMultiple(t *testing.T) {
-	d := NewDoubleArrayTrie()
+	d := NewDoubleArrayTrie[int]()
 
 	d.setBase(1, 1)
 	d.setBase(2, 1)
@@ -169,7 +258,7 @@ func TestFindArcsInTrieMultiple(t *testing.T) {
 }
 
 func TestXCheckInTrie(t *testing.T) {
-	d := NewDoubleArrayTrie()
+	d := NewDoubleArrayTrie[int]()
 
 	d.setCheck(1, 3)
 	d.setCheck(2, 1)
@@ -183,7 +272,7 @@ func TestXCheckInTrie(t *testing.T) {
 }
 
 func TestXCheckInTrieMultipleNoMatch(t *testing.T) {
-	d := NewDoubleArrayTrie()
+	d := NewDoubleArrayTrie[int]()
 
 	d.setCheck(1, 3)
 	d.setCheck(2, 1)
@@ -195,7 +284,7 @@ func TestXCheckInTrieMultipleNoMatch(t *testing.T) {
 }
 
 func TestXCheckInTrieMultipleWithMatch(t *testing.T) {
-	d := NewDoubleArrayTrie()
+	d := NewDoubleArrayTrie[int]()
 
 	d.setCheck(1, 3)
 	d.setCheck(2, 1)
@@ -209,70 +298,593 @@ func TestXCheckInTrieMultipleWithMatch(t *testing.T) {
 }
 
 func TestXAddInTrieEmpty(t *testing.T) {
-	d := NewDoubleArrayTrie()
+	d := NewDoubleArrayTrie[int]()
 
-	d.Add("bachelor")
-	d.Add("jar")
+	d.Put("bachelor", 1)
+	d.Put("jar", 2)
 
-	if d.tail != "achelor#ar#" {
-		t.Errorf("expected tail to be %v, got %v", "achelor#ar#", d.tail)
+	if string(d.tail) != "achelorar" {
+		t.Errorf("expected tail to be %q, got %q", "achelorar", d.tail)
 	}
 
-	if d.tailPos != 12 {
-		t.Errorf("expected tailPos to be %v, got %v", 11, d.tailPos)
+	if len(d.tails) != 2 {
+		t.Errorf("expected %v tail segments, got %v", 2, len(d.tails))
 	}
 
-	if d.getBase(3) != -1 {
-		t.Errorf("expected getBase for pos %v to be %v, got %v", 3, -1, d.getBase(3))
+	pos := d.getBase(1) + ValueFromChar('b')
+
+	if d.getBase(pos) != -1 {
+		t.Errorf("expected getBase for pos %v to be %v, got %v", pos, -1, d.getBase(pos))
 	}
 
-	if d.getCheck(3) != 1 {
-		t.Errorf("expected getCheck for pos %v to be %v, got %v", 3, 1, d.getCheck(3))
+	if d.getCheck(pos) != 1 {
+		t.Errorf("expected getCheck for pos %v to be %v, got %v", pos, 1, d.getCheck(pos))
 	}
 }
 
 
 func TestXAddInTrieWithNoCommonPrefix(t *testing.T) {
-	d := NewDoubleArrayTrie()
+	d := NewDoubleArrayTrie[int]()
 
-	d.Add("bachelor")
-	d.Add("jar")
+	d.Put("bachelor", 1)
+	d.Put("jar", 2)
 
-	if d.Get("bachelor") != true {
-		t.Errorf("expected Get for % to be %v, got %v", "bachelor", true, d.Get("bachelor"))
+	if value, _ := d.Get("bachelor"); value != 1 {
+		t.Errorf("expected Get for %v to be %v, got %v", "bachelor", 1, value)
 	}
 
-	if d.Get("jar") != true {
-		t.Errorf("expected Get for % to be %v, got %v", "jar", true, d.Get("jar"))
+	if value, _ := d.Get("jar"); value != 2 {
+		t.Errorf("expected Get for %v to be %v, got %v", "jar", 2, value)
 	}
 }
 
 func TestXAddInTrieWithCommonPrefix(t *testing.T) {
-	d := NewDoubleArrayTrie()
+	d := NewDoubleArrayTrie[int]()
+
+	d.Put("bachelor", 1)
+	d.Put("jar", 2)
+	d.Put("badge", 3)
+
+	if value, _ := d.Get("bachelor"); value != 1 {
+		t.Errorf("expected Get for %v to be %v, got %v", "bachelor", 1, value)
+	}
+
+	if value, _ := d.Get("jar"); value != 2 {
+		t.Errorf("expected Get for %v to be %v, got %v", "jar", 2, value)
+	}
+
+	if value, _ := d.Get("badge"); value != 3 {
+		t.Errorf("expected Get for %v to be %v, got %v", "badge", 3, value)
+	}
+}
+
+func TestPutReplacesValue(t *testing.T) {
+	d := NewDoubleArrayTrie[int]()
+
+	if _, replaced := d.Put("bachelor", 1); replaced != false {
+		t.Errorf("expected Put for new key %v to replace %v, got %v", "bachelor", false, replaced)
+	}
+
+	if old, replaced := d.Put("bachelor", 2); old != 1 || replaced != true {
+		t.Errorf("expected Put for %v to replace %v, got %v", "bachelor", 1, old)
+	}
+
+	if value, _ := d.Get("bachelor"); value != 2 {
+		t.Errorf("expected Get for %v to be %v, got %v", "bachelor", 2, value)
+	}
+
+	d.Put("bachelor", 0)
+
+	if value, ok := d.Get("bachelor"); value != 0 || ok != true {
+		t.Errorf("expected Get for %v to be %v, got %v", "bachelor", 0, value)
+	}
+
+	if d.Len() != 1 {
+		t.Errorf("expected Len to be %v, got %v", 1, d.Len())
+	}
+}
+
+func TestPutFullByteAlphabet(t *testing.T) {
+	d := NewDoubleArrayTrie[int]()
+
+	keys := []string{"SKU-001", "sku-001", "A b#C", "#", "", "a", "ab", "abc", "日本語", "日本", "Ünïcödé", "\xff\x01"}
+	for i, key := range keys {
+		if _, replaced := d.Put(key, i); replaced != false {
+			t.Errorf("expected Put for %q to replace %v, got %v", key, false, true)
+		}
+	}
+
+	for i, key := range keys {
+		if value, _ := d.Get(key); value != i {
+			t.Errorf("expected Get for %q to be %v, got %v", key, i, value)
+		}
+	}
+
+	for _, key := range []string{"SKU", "abcd", "日", "b", "##"} {
+		if _, ok := d.Get(key); ok != false {
+			t.Errorf("expected Get for %q to be %v, got %v", key, false, true)
+		}
+	}
+}
+
+func TestPutKeyWithTerminator(t *testing.T) {
+	d := NewDoubleArrayTrie[int]()
+
+	if d.ValidKey("ab\x00c") != false {
+		t.Errorf("expected ValidKey for key with terminator to be %v, got %v", false, true)
+	}
+
+	if d.ValidKey("abc") != true {
+		t.Errorf("expected ValidKey for %q to be %v, got %v", "abc", true, false)
+	}
+
+	d.Put("ab\x00c", 1)
+
+	if d.Len() != 0 {
+		t.Errorf("expected Len after Put of key with terminator to be %v, got %v", 0, d.Len())
+	}
+
+	if _, ok := d.Get("ab"); ok != false {
+		t.Errorf("expected Get for %q to be %v, got %v", "ab", false, true)
+	}
+}
+
+func TestGetKeyWithTerminator(t *testing.T) {
+	d := NewDoubleArrayTrie[int]()
+	d.Put("a", 1)
+	d.Put("ab", 2)
+
+	if _, ok := d.Get("a\x00"); ok != false {
+		t.Errorf("expected Get for %q to be %v, got %v", "a\x00", false, true)
+	}
+
+	if _, ok := d.Delete("a\x00"); ok != false {
+		t.Errorf("expected Delete for %q to be %v, got %v", "a\x00", false, true)
+	}
+
+	if d.Len() != 2 {
+		t.Errorf("expected Len to be %v, got %v", 2, d.Len())
+	}
+
+	if v, ok := d.Get("a"); ok != true || v != 1 {
+		t.Errorf("expected Get for %q to be %v, got %v", "a", 1, v)
+	}
+}
+
+func TestWalkPrefix(t *testing.T) {
+	d := NewDoubleArrayTrie[int]()
+
+	for i, key := range []string{"bachelor", "jar", "badge", "baby", "bad", "ba", "b", "jarring"} {
+		d.Put(key, i)
+	}
+
+	cases := []struct {
+		prefix string
+		keys   []string
+	}{
+		{"ba", []string{"ba", "baby", "bachelor", "bad", "badge"}},
+		{"bache", []string{"bachelor"}},
+		{"bachx", nil},
+		{"jarr", []string{"jarring"}},
+		{"", []string{"b", "ba", "baby", "bachelor", "bad", "badge", "jar", "jarring"}},
+		{"x", nil},
+	}
+
+	for _, c := range cases {
+		var keys []string
+		d.WalkPrefix(c.prefix, func(key string, value int) bool {
+			keys = append(keys, key)
+			return true
+		})
+		if !reflect.DeepEqual(keys, c.keys) {
+			t.Errorf("expected WalkPrefix for %q to yield %v, got %v", c.prefix, c.keys, keys)
+		}
+	}
+
+	var keys []string
+	d.WalkPrefix("ba", func(key string, value int) bool {
+		keys = append(keys, key)
+		return len(keys) < 3
+	})
+	if !reflect.DeepEqual(keys, []string{"ba", "baby", "bachelor"}) {
+		t.Errorf("expected stopped WalkPrefix to yield %v, got %v", []string{"ba", "baby", "bachelor"}, keys)
+	}
+}
+
+func TestWalkPrefixRandomKeys(t *testing.T) {
+	r := mrand.New(mrand.NewSource(2))
+	d := NewDoubleArrayTrie[string]()
+
+	var expected []string
+	for key := range randomKeys(r, 1000, true) {
+		d.Put(key, key)
+		expected = append(expected, key)
+	}
+	sort.Strings(expected)
+
+	var keys []string
+	d.WalkPrefix("", func(key string, value string) bool {
+		if key != value {
+			t.Fatalf("expected WalkPrefix value for %q to be %q, got %q", key, key, value)
+		}
+		keys = append(keys, key)
+		return true
+	})
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected WalkPrefix to yield all %v keys in order, got %v keys", len(expected), len(keys))
+	}
+}
+
+func TestCommonPrefixSearch(t *testing.T) {
+	d := NewDoubleArrayTrie[int]()
+
+	for i, key := range []string{"", "b", "ba", "bad", "badge", "bachelor", "jar"} {
+		d.Put(key, i)
+	}
+
+	cases := []struct {
+		input   string
+		matches []Match[int]
+	}{
+		{"badges", []Match[int]{{"", 0, 0}, {"b", 1, 1}, {"ba", 2, 2}, {"bad", 3, 3}, {"badge", 5, 4}}},
+		{"bachelors", []Match[int]{{"", 0, 0}, {"b", 1, 1}, {"ba", 2, 2}, {"bachelor", 8, 5}}},
+		{"bache", []Match[int]{{"", 0, 0}, {"b", 1, 1}, {"ba", 2, 2}}},
+		{"jarring", []Match[int]{{"", 0, 0}, {"jar", 3, 6}}},
+		{"x", []Match[int]{{"", 0, 0}}},
+	}
+
+	for _, c := range cases {
+		matches := d.CommonPrefixSearch(c.input)
+		if !reflect.DeepEqual(matches, c.matches) {
+			t.Errorf("expected CommonPrefixSearch for %q to be %v, got %v", c.input, c.matches, matches)
+		}
+	}
+
+	d.Delete("")
+	var lengths []int
+	d.CommonPrefixSearchFunc("badge", func(length int, value int) bool {
+		lengths = append(lengths, length)
+		return length < 2
+	})
+	if !reflect.DeepEqual(lengths, []int{1, 2}) {
+		t.Errorf("expected stopped CommonPrefixSearchFunc to yield %v, got %v", []int{1, 2}, lengths)
+	}
+}
+
+func TestLongestPrefix(t *testing.T) {
+	d := NewDoubleArrayTrie[int]()
+
+	for i, key := range []string{"/api", "/api/v1", "/api/v1/users", "/static"} {
+		d.Put(key, i)
+	}
+
+	cases := []struct {
+		key     string
+		matched string
+		value   int
+		ok      bool
+	}{
+		{"/api/v1/users/42", "/api/v1/users", 2, true},
+		{"/api/v1/orders", "/api/v1", 1, true},
+		{"/api/v2", "/api", 0, true},
+		{"/static", "/static", 3, true},
+		{"/stat", "", 0, false},
+		{"", "", 0, false},
+	}
+
+	for _, c := range cases {
+		matched, value, ok := d.LongestPrefix(c.key)
+		if matched != c.matched || value != c.value || ok != c.ok {
+			t.Errorf("expected LongestPrefix for %q to be (%q, %v, %v), got (%q, %v, %v)", c.key, c.matched, c.value, c.ok, matched, value, ok)
+		}
+	}
+}
+
+func TestIterator(t *testing.T) {
+	d := NewDoubleArrayTrie[int]()
+
+	keys := []string{"", "b", "ba", "baby", "bachelor", "bad", "badge", "jar"}
+	for i, key := range keys {
+		d.Put(key, i)
+	}
 
-	d.Add("bachelor")
-	d.Add("jar")
-	d.Add("badge")
+	it := d.Iterator()
+	for i, key := range keys {
+		if it.Next() != true || it.Key() != key || it.Value() != i {
+			t.Fatalf("expected Next to move to %q, got %q", key, it.Key())
+		}
+	}
+	if it.Next() != false {
+		t.Errorf("expected Next past the last key to be %v, got %v", false, true)
+	}
+	for i := len(keys) - 1; i >= 0; i-- {
+		if it.Prev() != true || it.Key() != keys[i] || it.Value() != i {
+			t.Fatalf("expected Prev to move to %q, got %q", keys[i], it.Key())
+		}
+	}
+	if it.Prev() != false {
+		t.Errorf("expected Prev before the first key to be %v, got %v", false, true)
+	}
+}
+
+func TestSeek(t *testing.T) {
+	d := NewDoubleArrayTrie[int]()
+
+	for i, key := range []string{"b", "ba", "baby", "bachelor", "bad", "badge", "jar"} {
+		d.Put(key, i)
+	}
+
+	cases := []struct {
+		key  string
+		next string
+		prev string
+	}{
+		{"", "b", ""},
+		{"ba", "ba", "b"},
+		{"bab", "baby", "ba"},
+		{"babz", "bachelor", "baby"},
+		{"bachelor", "bachelor", "baby"},
+		{"bachelors", "bad", "bachelor"},
+		{"bacz", "bad", "bachelor"},
+		{"c", "jar", "badge"},
+		{"z", "", "jar"},
+	}
+
+	for _, c := range cases {
+		it := d.Seek(c.key)
+		if it.Next(); it.Key() != c.next {
+			t.Errorf("expected Next after Seek for %q to be %q, got %q", c.key, c.next, it.Key())
+		}
+		it = d.Seek(c.key)
+		if it.Prev(); it.Key() != c.prev {
+			t.Errorf("expected Prev after Seek for %q to be %q, got %q", c.key, c.prev, it.Key())
+		}
+	}
+}
+
+func TestSeekRandomKeys(t *testing.T) {
+	r := mrand.New(mrand.NewSource(3))
+	d := NewDoubleArrayTrie[string]()
+
+	var keys []string
+	for key := range randomKeys(r, 500, true) {
+		d.Put(key, key)
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for key := range randomKeys(r, 500, true) {
+		i := sort.SearchStrings(keys, key)
+		it := d.Seek(key)
+		for j := i; j < i+3 && j < len(keys); j++ {
+			if it.Next() != true || it.Key() != keys[j] || it.Value() != keys[j] {
+				t.Fatalf("expected Next after Seek for %q to be %q, got %q", key, keys[j], it.Key())
+			}
+		}
+		it = d.Seek(key)
+		for j := i - 1; j > i-4 && j >= 0; j-- {
+			if it.Prev() != true || it.Key() != keys[j] {
+				t.Fatalf("expected Prev after Seek for %q to be %q, got %q", key, keys[j], it.Key())
+			}
+		}
+	}
+}
+
+// Returns n random keys. Binary keys use every byte but the terminator,
+// otherwise keys are random UTF-8 strings.
+func randomKeys(r *mrand.Rand, n int, binary bool) map[string]bool {
+	keys := make(map[string]bool, n)
+	for len(keys) < n {
+		length := r.Intn(12)
+		var key []byte
+		for i := 0; i < length; i++ {
+			if binary {
+				key = append(key, byte(minCode+r.Intn(maxCode-minCode+1)))
+			} else {
+				key = utf8.AppendRune(key, rune(minCode+r.Intn(0x3000)))
+			}
+		}
+		keys[string(key)] = true
+	}
+	return keys
+}
+
+func testRoundTrip(t *testing.T, binary bool) {
+	r := mrand.New(mrand.NewSource(1))
+	d := NewDoubleArrayTrie[string]()
+
+	keys := randomKeys(r, 2000, binary)
+	for key := range keys {
+		d.Put(key, key)
+	}
+
+	for key := range keys {
+		if value, _ := d.Get(key); value != key {
+			t.Fatalf("expected Get for %q to be %q, got %v", key, key, value)
+		}
+	}
+
+	for key := range randomKeys(r, 2000, binary) {
+		if _, ok := d.Get(key); ok != keys[key] {
+			t.Fatalf("expected Get for %q to be %v, got %v", key, keys[key], !keys[key])
+		}
+	}
+
+	deleted := 0
+	for key := range keys {
+		if deleted%2 == 0 {
+			if value, ok := d.Delete(key); value != key || ok != true {
+				t.Fatalf("expected Delete for %q to be %v, got %v", key, true, false)
+			}
+			keys[key] = false
+		}
+		deleted++
+	}
+
+	for key, present := range keys {
+		if _, ok := d.Get(key); ok != present {
+			t.Fatalf("expected Get for %q to be %v, got %v", key, present, !present)
+		}
+	}
+	if err := d.Validate(); err != nil {
+		t.Fatalf("expected a valid trie, got %v", err)
+	}
+}
+
+func TestRoundTripBinaryKeys(t *testing.T) {
+	testRoundTrip(t, true)
+}
+
+func TestRoundTripUTF8Keys(t *testing.T) {
+	testRoundTrip(t, false)
+}
+
+// Returns the number of states in use
+func usedCells[V any](d *DoubleArrayTrie[V]) int {
+	n := 0
+	for pos := 1; pos <= len(d.check); pos++ {
+		if pos == 1 || d.getCheck(pos) != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func TestDeleteRemovesAncestors(t *testing.T) {
+	d := NewDoubleArrayTrie[int]()
+	d.Put("bachelor", 1)
+	d.Put("badge", 2)
+	d.Put("badger", 3)
+
+	d.Delete("badger")
+	d.Delete("badge")
+
+	fresh := NewDoubleArrayTrie[int]()
+	fresh.Put("bachelor", 1)
+	if usedCells(d) != usedCells(fresh) {
+		t.Errorf("expected %v states in use, got %v", usedCells(fresh), usedCells(d))
+	}
+	if value, ok := d.Get("bachelor"); value != 1 || ok != true {
+		t.Errorf("expected Get for %v to be %v, got %v", "bachelor", 1, value)
+	}
+	if _, ok := d.Get("badge"); ok != false {
+		t.Errorf("expected Get for %v to be %v, got %v", "badge", false, ok)
+	}
+
+	d.Delete("bachelor")
+	if usedCells(d) != 1 {
+		t.Errorf("expected %v states in use, got %v", 1, usedCells(d))
+	}
+}
+
+func TestDeleteReusesPositions(t *testing.T) {
+	r := mrand.New(mrand.NewSource(21))
+	d := NewDoubleArrayTrie[string]()
+
+	var cells int
+	for round := 0; round < 10; round++ {
+		// Sorted so that the layout does not depend on map order
+		keys, _ := sortedKeyValues(randomKeys(r, 500, true))
+		for _, key := range keys {
+			d.Put(key, key)
+		}
+		for _, key := range keys {
+			if value, ok := d.Delete(key); value != key || ok != true {
+				t.Fatalf("expected Delete for %q to be %v, got %v", key, true, ok)
+			}
+		}
+
+		if usedCells(d) != 1 {
+			t.Fatalf("expected %v states in use, got %v", 1, usedCells(d))
+		}
+		if err := d.Validate(); err != nil {
+			t.Fatalf("expected a valid trie, got %v", err)
+		}
+		if round == 0 {
+			cells = len(d.check)
+		} else if len(d.check) != cells {
+			t.Fatalf("expected the arrays to keep %v positions, got %v", cells, len(d.check))
+		}
+	}
+}
+
+func TestCompact(t *testing.T) {
+	r := mrand.New(mrand.NewSource(3))
+	d := NewDoubleArrayTrie[string]()
+
+	keys := randomKeys(r, 2000, false)
+	for key := range keys {
+		d.Put(key, key)
+	}
+	deleted := 0
+	for key := range keys {
+		if deleted%3 != 0 {
+			d.Delete(key)
+			delete(keys, key)
+		}
+		deleted++
+	}
+
+	tailLen := len(d.tail)
+	if reclaimed := d.Compact(); reclaimed <= 0 {
+		t.Errorf("expected Compact to reclaim bytes, got %v", reclaimed)
+	}
+	if len(d.tail) >= tailLen {
+		t.Errorf("expected the tail to shrink below %v bytes, got %v", tailLen, len(d.tail))
+	}
+	if d.getCheck(len(d.check)) == 0 {
+		t.Errorf("expected the last position to be in use")
+	}
+	if err := d.Validate(); err != nil {
+		t.Errorf("expected a valid trie, got %v", err)
+	}
 
-	if d.Get("bachelor") != true {
-		t.Errorf("expected Get for % to be %v, got %v", "bachelor", true, d.Get("bachelor"))
+	var expected []string
+	for key := range keys {
+		expected = append(expected, key)
+		if value, ok := d.Get(key); value != key || ok != true {
+			t.Fatalf("expected Get for %q to be %q, got %q", key, key, value)
+		}
+	}
+	sort.Strings(expected)
+	var walked []string
+	d.WalkPrefix("", func(key string, value string) bool {
+		walked = append(walked, key)
+		return true
+	})
+	if !reflect.DeepEqual(walked, expected) {
+		t.Errorf("expected WalkPrefix to yield %v keys in order, got %v", len(expected), len(walked))
 	}
 
-	if d.Get("jar") != true {
-		t.Errorf("expected Get for % to be %v, got %v", "jar", true, d.Get("jar"))
+	if reclaimed := d.Compact(); reclaimed != 0 {
+		t.Errorf("expected a second Compact to reclaim %v bytes, got %v", 0, reclaimed)
 	}
 
-	if d.Get("badge") != true {
-		t.Errorf("expected Get for % to be %v, got %v", "badge", true, d.Get("badge"))
+	d.Put("after compact", "after compact")
+	if value, _ := d.Get("after compact"); value != "after compact" {
+		t.Errorf("expected Get for %q to be %q, got %q", "after compact", "after compact", value)
+	}
+}
+
+func BenchmarkDoubleArrayTriePutDeleteChurn(b *testing.B) {
+	d := NewDoubleArrayTrie[int]()
+	for i := 0; i < len(words)/2; i++ {
+		d.Put(words[i], i)
+	}
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d.Put(words[len(words)/2+i%(len(words)/2)], i)
+		d.Delete(words[len(words)/2+(i+len(words)/4)%(len(words)/2)])
 	}
 }
 
 func BenchmarkDoubleArrayTrieGetSimpleStringKey(b *testing.B) {
-	d := NewDoubleArrayTrie()
+	d := NewDoubleArrayTrie[int]()
 
 	words := [...]string{"hellohasdhwd ed  qqdwd", "baby", "are", "you", "today", "babe", "hare", "hake", "sake"}
-	for _, word := range words {
-		d.Add(word)
+	for i, word := range words {
+		d.Put(word, i)
 	}
 
 	b.ResetTimer()
@@ -282,3 +894,79 @@ func BenchmarkDoubleArrayTrieGetSimpleStringKey(b *testing.B) {
 		d.Get(words[i%len(words)])
 	}
 }
+
+func BenchmarkDoubleArrayTrieCommonPrefixSearch(b *testing.B) {
+	d := NewDoubleArrayTrie[int]()
+
+	words := [...]string{"b", "ba", "bab", "baby", "babe", "hare", "hake", "sake"}
+	for i, word := range words {
+		d.Put(word, i)
+	}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		d.CommonPrefixSearchFunc("babylon", func(length int, value int) bool {
+			return true
+		})
+	}
+}
+
+func BenchmarkDoubleArrayTrieLongestPrefix(b *testing.B) {
+	d := NewDoubleArrayTrie[int]()
+
+	words := [...]string{"/api", "/api/v1", "/api/v1/users", "/static", "/static/css"}
+	for i, word := range words {
+		d.Put(word, i)
+	}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		d.LongestPrefix("/api/v1/users/42")
+	}
+}
+
+func BenchmarkDoubleArrayTriePutStringKey(b *testing.B) {
+	trie := NewDoubleArrayTrie[int]()
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trie.Put(words[i%len(words)], i)
+	}
+}
+
+func BenchmarkDoubleArrayTrieGetStringKey(b *testing.B) {
+	trie := NewDoubleArrayTrie[int]()
+	for i := 0; i < len(words); i++ {
+		trie.Put(words[i], i)
+	}
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trie.Get(words[i%len(words)])
+	}
+}
+
+func BenchmarkDoubleArrayTriePutPhraseKey(b *testing.B) {
+	trie := NewDoubleArrayTrie[int]()
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trie.Put(phrases[i%len(phrases)], i)
+	}
+}
+
+func BenchmarkDoubleArrayTrieGetPhraseKey(b *testing.B) {
+	trie := NewDoubleArrayTrie[int]()
+	for i := 0; i < len(phrases); i++ {
+		trie.Put(phrases[i], i)
+	}
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trie.Get(phrases[i%len(phrases)])
+	}
+}
@@ -0,0 +1,38 @@
+package go_tries
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrKeyNotFound is returned by Lookup when a key is not stored.
+	ErrKeyNotFound = errors.New("go_tries: key not found")
+	// ErrInvalidKey is returned for keys a trie cannot store, such as keys
+	// holding 0x00 in a DoubleArrayTrie.
+	ErrInvalidKey = errors.New("go_tries: invalid key")
+	// ErrCapacity is returned when a trie cannot grow to hold a key.
+	ErrCapacity = errors.New("go_tries: capacity exceeded")
+	// ErrOutOfRange is returned for positions outside the tail of a
+	// DoubleArrayTrie.
+	ErrOutOfRange = errors.New("go_tries: position out of range")
+
+	// ErrCorrupt is returned when serialized data cannot be loaded. The
+	// more specific errors below wrap it.
+	ErrCorrupt = errors.New("go_tries: corrupt data")
+	// ErrBadMagic is returned when data does not start with the expected
+	// magic bytes.
+	ErrBadMagic = fmt.Errorf("%w: bad magic", ErrCorrupt)
+	// ErrVersion is returned when data was written in a format version this
+	// package does not read.
+	ErrVersion = fmt.Errorf("%w: unsupported version", ErrCorrupt)
+	// ErrTruncated is returned when data ends early.
+	ErrTruncated = fmt.Errorf("%w: truncated", ErrCorrupt)
+	// ErrChecksum is returned when data does not match its checksum.
+	ErrChecksum = fmt.Errorf("%w: checksum mismatch", ErrCorrupt)
+)
+
+// Returns ErrKeyNotFound for key
+func notFound(key string) error {
+	return fmt.Errorf("%w: %q", ErrKeyNotFound, key)
+}
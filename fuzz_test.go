@@ -0,0 +1,136 @@
+package go_tries
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Operations decoded by FuzzTrieOps
+const (
+	opPut = iota
+	opGet
+	opDelete
+	opWalkPrefix
+	opCompact
+	opCount
+)
+
+// Splits the next operation off a fuzz input: an operation byte, a key
+// length byte and that many key bytes. Inputs ending early yield a shorter
+// key. Returns false at the end of the input.
+func nextOp(data []byte) (op int, key string, rest []byte, ok bool) {
+	if len(data) < 2 {
+		return 0, "", nil, false
+	}
+	op, length := int(data[0])%opCount, int(data[1])%16
+	data = data[2:]
+	length = min(length, len(data))
+	return op, string(data[:length]), data[length:], true
+}
+
+// Returns the keys of ref that start with prefix in byte order
+func refPrefix(ref map[string]int, prefix string) []string {
+	var keys []string
+	for key := range ref {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Returns the keys WalkPrefix visits
+func walkedKeys(walk func(prefix string, fn func(key string, value int) bool), prefix string) []string {
+	var keys []string
+	walk(prefix, func(key string, value int) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// FuzzTrieOps applies a sequence of operations decoded from the input to a
+// SimpleTrie splitting keys into bytes, a DoubleArrayTrie and a map, and
+// fails when they disagree.
+func FuzzTrieOps(f *testing.F) {
+	f.Add([]byte{opPut, 3, 'a', 'b', 'c', opPut, 2, 'a', 'b', opGet, 3, 'a', 'b', 'c', opDelete, 2, 'a', 'b', opWalkPrefix, 1, 'a'})
+	f.Add([]byte{opPut, 0, opPut, 1, 'a', opDelete, 0, opWalkPrefix, 0, opCompact, 0, opGet, 1, 'a'})
+	f.Add([]byte{opPut, 2, 0xff, 0xfe, opPut, 2, 0xff, 0x01, opPut, 2, 0x01, 0xff, opDelete, 2, 0xff, 0xfe, opCompact, 0})
+	f.Add([]byte{opPut, 3, 'a', 0, 'b', opGet, 3, 'a', 0, 'b', opWalkPrefix, 1, 'a'})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		simple := NewSimpleTrie[int](WithTokenizer(ByteTokenizer{}))
+		d := NewDoubleArrayTrie[int]()
+		ref := make(map[string]int)
+
+		for i := 0; ; i++ {
+			op, key, rest, ok := nextOp(data)
+			if !ok {
+				break
+			}
+			data = rest
+
+			switch op {
+			case opPut:
+				if !d.ValidKey(key) {
+					if err := d.Insert(key, i); !errors.Is(err, ErrInvalidKey) {
+						t.Fatalf("expected Insert for %q to fail with %v, got %v", key, ErrInvalidKey, err)
+					}
+					continue
+				}
+				expected, expectedOk := ref[key]
+				ref[key] = i
+				if old, replaced := simple.Put(key, i); old != expected || replaced != expectedOk {
+					t.Fatalf("expected SimpleTrie Put for %q to be (%v, %v), got (%v, %v)", key, expected, expectedOk, old, replaced)
+				}
+				if old, replaced := d.Put(key, i); old != expected || replaced != expectedOk {
+					t.Fatalf("expected DoubleArrayTrie Put for %q to be (%v, %v), got (%v, %v)", key, expected, expectedOk, old, replaced)
+				}
+			case opGet:
+				expected, expectedOk := ref[key]
+				if value, ok := simple.Get(key); value != expected || ok != expectedOk {
+					t.Fatalf("expected SimpleTrie Get for %q to be (%v, %v), got (%v, %v)", key, expected, expectedOk, value, ok)
+				}
+				if value, ok := d.Get(key); value != expected || ok != expectedOk {
+					t.Fatalf("expected DoubleArrayTrie Get for %q to be (%v, %v), got (%v, %v)", key, expected, expectedOk, value, ok)
+				}
+			case opDelete:
+				expected, expectedOk := ref[key]
+				delete(ref, key)
+				if value, ok := simple.Delete(key); value != expected || ok != expectedOk {
+					t.Fatalf("expected SimpleTrie Delete for %q to be (%v, %v), got (%v, %v)", key, expected, expectedOk, value, ok)
+				}
+				if value, ok := d.Delete(key); value != expected || ok != expectedOk {
+					t.Fatalf("expected DoubleArrayTrie Delete for %q to be (%v, %v), got (%v, %v)", key, expected, expectedOk, value, ok)
+				}
+			case opWalkPrefix:
+				expected := refPrefix(ref, key)
+				if keys := walkedKeys(simple.WalkPrefix, key); !reflect.DeepEqual(keys, expected) {
+					t.Fatalf("expected SimpleTrie WalkPrefix for %q to yield %q, got %q", key, expected, keys)
+				}
+				if keys := walkedKeys(d.WalkPrefix, key); !reflect.DeepEqual(keys, expected) {
+					t.Fatalf("expected DoubleArrayTrie WalkPrefix for %q to yield %q, got %q", key, expected, keys)
+				}
+			case opCompact:
+				d.Compact()
+			}
+
+			if err := d.Validate(); err != nil {
+				t.Fatalf("expected a valid DoubleArrayTrie after operation %v, got %v", i, err)
+			}
+		}
+
+		if simple.Len() != len(ref) || d.Len() != len(ref) {
+			t.Fatalf("expected Len to be %v, got %v and %v", len(ref), simple.Len(), d.Len())
+		}
+		for key, value := range ref {
+			if got, ok := d.Get(key); got != value || ok != true {
+				t.Fatalf("expected DoubleArrayTrie Get for %q to be (%v, %v), got (%v, %v)", key, value, true, got, ok)
+			}
+		}
+	})
+}
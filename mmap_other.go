@@ -0,0 +1,15 @@
+//go:build !unix
+
+package go_tries
+
+import "os"
+
+// Reads the file at path into memory on platforms without mmap. Returns
+// its bytes and a function that does nothing.
+func mapFile(path string) ([]byte, func() error, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
@@ -0,0 +1,219 @@
+package go_tries
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// PersistentRadixTree is a RadixTree whose nodes are never modified once
+// published. Writes copy the nodes on the path to the changed key and
+// publish the new root atomically, so readers never lock and always see a
+// consistent tree. Writers are serialized by a mutex. It is safe for
+// concurrent use.
+type PersistentRadixTree[V any] struct {
+	// Serializes writers
+	mu sync.Mutex
+	// Current version of the tree
+	root atomic.Pointer[RadixSnapshot[V]]
+}
+
+// RadixSnapshot is an immutable version of a PersistentRadixTree. It can
+// be read and walked while the tree it was taken from changes.
+type RadixSnapshot[V any] struct {
+	// Root node. Its prefix is always empty
+	root *radixNode[V]
+	// Number of keys stored
+	size int
+}
+
+// NewPersistentRadixTree allocates and returns a new *PersistentRadixTree.
+func NewPersistentRadixTree[V any]() *PersistentRadixTree[V] {
+	tree := &PersistentRadixTree[V]{}
+	tree.root.Store(&RadixSnapshot[V]{root: &radixNode[V]{}})
+	return tree
+}
+
+// Snapshot returns the current version of the tree, which later writes do
+// not change.
+func (tree *PersistentRadixTree[V]) Snapshot() *RadixSnapshot[V] {
+	return tree.root.Load()
+}
+
+// Len returns the number of keys stored in the tree.
+func (tree *PersistentRadixTree[V]) Len() int {
+	return tree.Snapshot().Len()
+}
+
+// Get returns the value stored at the given key and whether the key was
+// found. It does not lock.
+func (tree *PersistentRadixTree[V]) Get(key string) (V, bool) {
+	return tree.Snapshot().Get(key)
+}
+
+// Lookup returns the value stored at the given key or ErrKeyNotFound if
+// the key is not stored.
+func (tree *PersistentRadixTree[V]) Lookup(key string) (V, error) {
+	value, ok := tree.Get(key)
+	if !ok {
+		return value, notFound(key)
+	}
+	return value, nil
+}
+
+// Insert stores value at the given key. Every key can be stored, so it
+// always returns nil.
+func (tree *PersistentRadixTree[V]) Insert(key string, value V) error {
+	tree.Put(key, value)
+	return nil
+}
+
+// WalkPrefix calls fn for every key that starts with prefix, until fn
+// returns false. Keys are visited in ascending byte order, in the version
+// of the tree current when WalkPrefix was called.
+func (tree *PersistentRadixTree[V]) WalkPrefix(prefix string, fn func(key string, value V) bool) {
+	tree.Snapshot().WalkPrefix(prefix, fn)
+}
+
+// Put stores value at the given key. It returns the previous value and
+// whether it was replaced.
+func (tree *PersistentRadixTree[V]) Put(key string, value V) (V, bool) {
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+
+	current := tree.root.Load()
+	root, old, replaced := current.root.put(key, value)
+	size := current.size
+	if !replaced {
+		size += 1
+	}
+	tree.root.Store(&RadixSnapshot[V]{root: root, size: size})
+	return old, replaced
+}
+
+// Delete removes the given key. It returns the removed value and whether
+// the key was found.
+func (tree *PersistentRadixTree[V]) Delete(key string) (V, bool) {
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+
+	current := tree.root.Load()
+	root, old, ok := current.root.remove(key, true)
+	if ok {
+		tree.root.Store(&RadixSnapshot[V]{root: root, size: current.size - 1})
+	}
+	return old, ok
+}
+
+// Len returns the number of keys stored in the snapshot.
+func (snapshot *RadixSnapshot[V]) Len() int {
+	return snapshot.size
+}
+
+// Get returns the value stored at the given key and whether the key was
+// found.
+func (snapshot *RadixSnapshot[V]) Get(key string) (V, bool) {
+	return snapshot.root.get(key)
+}
+
+// WalkPrefix calls fn for every key that starts with prefix, until fn
+// returns false. Keys are visited in ascending byte order.
+func (snapshot *RadixSnapshot[V]) WalkPrefix(prefix string, fn func(key string, value V) bool) {
+	snapshot.root.walkPrefix(prefix, fn)
+}
+
+// Returns a copy of node that can be changed without affecting node
+func (node *radixNode[V]) clone() *radixNode[V] {
+	c := *node
+	c.children = append([]*radixNode[V](nil), node.children...)
+	return &c
+}
+
+// Returns a copy of node with value stored at key below it, together with
+// the previous value and whether it was replaced. Only the nodes on the
+// path to key are copied.
+func (node *radixNode[V]) put(key string, value V) (*radixNode[V], V, bool) {
+	var zero V
+	n := node.clone()
+	if key == "" {
+		n.value, n.hasValue = value, true
+		return n, node.value, node.hasValue
+	}
+
+	i, ok := node.findChild(key[0])
+	if !ok {
+		n.insertChild(i, &radixNode[V]{prefix: key, value: value, hasValue: true})
+		return n, zero, false
+	}
+
+	child := node.children[i]
+	length := commonPrefixLen(key, child.prefix)
+	if length < len(child.prefix) {
+		// Split the edge at the end of the common prefix
+		tail := *child
+		tail.prefix = child.prefix[length:]
+		child = &radixNode[V]{
+			prefix:   child.prefix[:length],
+			children: []*radixNode[V]{&tail},
+		}
+	}
+
+	newChild, old, replaced := child.put(key[length:], value)
+	n.children[i] = newChild
+	return n, old, replaced
+}
+
+// Returns a copy of node without key below it, together with the removed
+// value and whether key was found. The copy is nil when nothing is left
+// of it, and nodes left with a single child and no value are merged with
+// it, except the root.
+func (node *radixNode[V]) remove(key string, root bool) (*radixNode[V], V, bool) {
+	var zero V
+	if key == "" {
+		if !node.hasValue {
+			return node, zero, false
+		}
+		n := node.clone()
+		n.value, n.hasValue = zero, false
+		return n.compact(root), node.value, true
+	}
+
+	i, ok := node.findChild(key[0])
+	if !ok || !strings.HasPrefix(key, node.children[i].prefix) {
+		return node, zero, false
+	}
+	child := node.children[i]
+	newChild, old, ok := child.remove(key[len(child.prefix):], false)
+	if !ok {
+		return node, zero, false
+	}
+
+	n := node.clone()
+	if newChild == nil {
+		n.removeChild(i)
+	} else {
+		n.children[i] = newChild
+	}
+	return n.compact(root), old, true
+}
+
+// Returns nil for a node without value or children and the merge of a node
+// without value with its only child. The root is returned unchanged.
+func (node *radixNode[V]) compact(root bool) *radixNode[V] {
+	if root || node.hasValue {
+		return node
+	}
+	switch len(node.children) {
+	case 0:
+		return nil
+	case 1:
+		child := node.children[0]
+		return &radixNode[V]{
+			prefix:   node.prefix + child.prefix,
+			children: child.children,
+			value:    child.value,
+			hasValue: child.hasValue,
+		}
+	}
+	return node
+}
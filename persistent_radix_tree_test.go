@@ -0,0 +1,204 @@
+package go_tries
+
+import (
+	"fmt"
+	mrand "math/rand"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// Returns the keys below prefix in walk order
+func snapshotKeys(s *RadixSnapshot[string], prefix string) []string {
+	var keys []string
+	s.WalkPrefix(prefix, func(key string, value string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// Returns the edges and values below node
+func radixShape(node *radixNode[string]) string {
+	shape := fmt.Sprintf("%q:%q{", node.prefix, node.value)
+	for _, child := range node.children {
+		shape += radixShape(child)
+	}
+	return shape + "}"
+}
+
+func TestPersistentRadixTreeRandomKeys(t *testing.T) {
+	rnd := mrand.New(mrand.NewSource(4))
+	p := NewPersistentRadixTree[string]()
+	r := NewRadixTree[string]()
+	ref := make(map[string]string)
+
+	for i := 0; i < 5000; i++ {
+		key := string(randomKeyFrom(rnd, "abc", 6))
+		if rnd.Intn(3) == 0 {
+			old, ok := p.Delete(key)
+			expected, exists := r.Delete(key)
+			if ok != exists || old != expected {
+				t.Fatalf("expected Delete for %q to be (%q, %v), got (%q, %v)", key, expected, exists, old, ok)
+			}
+			delete(ref, key)
+		} else {
+			value := fmt.Sprint(i)
+			old, replaced := p.Put(key, value)
+			expected, exists := r.Put(key, value)
+			if replaced != exists || old != expected {
+				t.Fatalf("expected Put for %q to be (%q, %v), got (%q, %v)", key, expected, exists, old, replaced)
+			}
+			ref[key] = value
+		}
+	}
+
+	if p.Len() != len(ref) {
+		t.Errorf("expected Len to be %v, got %v", len(ref), p.Len())
+	}
+
+	var expected []string
+	for key, value := range ref {
+		expected = append(expected, key)
+		if got, ok := p.Get(key); got != value || ok != true {
+			t.Errorf("expected Get for %q to be %q, got %q", key, value, got)
+		}
+	}
+	sort.Strings(expected)
+
+	if keys := snapshotKeys(p.Snapshot(), ""); !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected WalkPrefix to yield %v, got %v", expected, keys)
+	}
+	if shape, expected := radixShape(p.Snapshot().root), radixShape(r.root); shape != expected {
+		t.Errorf("expected the tree to have shape %v, got %v", expected, shape)
+	}
+}
+
+func TestPersistentRadixTreeSnapshot(t *testing.T) {
+	p := NewPersistentRadixTree[string]()
+	for _, key := range []string{"romane", "romanus", "romulus", "rubens"} {
+		p.Put(key, key)
+	}
+
+	snapshot := p.Snapshot()
+	p.Put("ruber", "ruber")
+	p.Put("romane", "changed")
+	p.Delete("romulus")
+	p.Delete("rubens")
+
+	expected := []string{"romane", "romanus", "romulus", "rubens"}
+	if keys := snapshotKeys(snapshot, "r"); !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected snapshot keys to be %v, got %v", expected, keys)
+	}
+	if value, _ := snapshot.Get("romane"); value != "romane" {
+		t.Errorf("expected snapshot value to be %q, got %q", "romane", value)
+	}
+	if snapshot.Len() != 4 {
+		t.Errorf("expected snapshot Len to be %v, got %v", 4, snapshot.Len())
+	}
+
+	expected = []string{"romane", "romanus", "ruber"}
+	if keys := snapshotKeys(p.Snapshot(), "r"); !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected tree keys to be %v, got %v", expected, keys)
+	}
+	if value, _ := p.Get("romane"); value != "changed" {
+		t.Errorf("expected tree value to be %q, got %q", "changed", value)
+	}
+}
+
+func TestPersistentRadixTreeConcurrentReaders(t *testing.T) {
+	const keys = 1000
+	p := NewPersistentRadixTree[string]()
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				// Keys are added in order, so every snapshot holds a
+				// contiguous run of them
+				snapshot := p.Snapshot()
+				walked := snapshotKeys(snapshot, "")
+				if len(walked) != snapshot.Len() {
+					t.Errorf("expected snapshot to walk %v keys, got %v", snapshot.Len(), len(walked))
+					return
+				}
+				for i, key := range walked {
+					if key != fmt.Sprintf("key %04d", i) {
+						t.Errorf("expected key %v to be %q, got %q", i, fmt.Sprintf("key %04d", i), key)
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("key %04d", i)
+		p.Put(key, key)
+	}
+	close(done)
+	wg.Wait()
+
+	if p.Len() != keys {
+		t.Errorf("expected Len to be %v, got %v", keys, p.Len())
+	}
+}
+
+func BenchmarkPersistentRadixTreePutStringKey(b *testing.B) {
+	trie := NewPersistentRadixTree[int]()
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trie.Put(words[i%len(words)], i)
+	}
+}
+
+func BenchmarkPersistentRadixTreeGetParallel(b *testing.B) {
+	trie := NewPersistentRadixTree[int]()
+	for i, word := range words {
+		trie.Put(word, i)
+	}
+	b.ResetTimer()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			trie.Get(words[i%len(words)])
+		}
+	})
+}
+
+func BenchmarkPersistentRadixTreeGetParallelWithWriter(b *testing.B) {
+	trie := NewPersistentRadixTree[int]()
+	for i, word := range words {
+		trie.Put(word, i)
+	}
+	done := make(chan struct{})
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+				trie.Put(words[i%len(words)], i)
+			}
+		}
+	}()
+	b.ResetTimer()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			trie.Get(words[i%len(words)])
+		}
+	})
+	b.StopTimer()
+	close(done)
+}
@@ -0,0 +1,240 @@
+package go_tries
+
+import (
+	"sort"
+	"strings"
+)
+
+// RadixTree is a path compressed trie. Every edge holds the longest run of
+// bytes shared by the keys below it, so a node exists only where keys
+// branch or end.
+type RadixTree[V any] struct {
+	// Root node. Its prefix is always empty
+	root *radixNode[V]
+	// Number of keys stored
+	size int
+}
+
+type radixNode[V any] struct {
+	// Bytes of the edge leading to the node
+	prefix string
+	// Children ordered by the first byte of their prefix
+	children []*radixNode[V]
+	// Value of Node
+	value V
+	// Whether the node holds a value
+	hasValue bool
+}
+
+// NewRadixTree allocates and returns a new *RadixTree.
+func NewRadixTree[V any]() *RadixTree[V] {
+	return &RadixTree[V]{
+		root: &radixNode[V]{},
+	}
+}
+
+// Returns the index of the child whose prefix starts with ch, or the index
+// it would be inserted at and false.
+func (node *radixNode[V]) findChild(ch byte) (int, bool) {
+	i := sort.Search(len(node.children), func(i int) bool {
+		return node.children[i].prefix[0] >= ch
+	})
+	return i, i < len(node.children) && node.children[i].prefix[0] == ch
+}
+
+func (node *radixNode[V]) insertChild(i int, child *radixNode[V]) {
+	node.children = append(node.children, nil)
+	copy(node.children[i+1:], node.children[i:])
+	node.children[i] = child
+}
+
+func (node *radixNode[V]) removeChild(i int) {
+	copy(node.children[i:], node.children[i+1:])
+	node.children[len(node.children)-1] = nil
+	node.children = node.children[:len(node.children)-1]
+}
+
+// Folds the only child of a node without value into it
+func (node *radixNode[V]) merge() {
+	child := node.children[0]
+	node.prefix += child.prefix
+	node.children = child.children
+	node.value = child.value
+	node.hasValue = child.hasValue
+}
+
+// Returns the length of the common prefix of a and b
+func commonPrefixLen(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i += 1
+	}
+	return i
+}
+
+// Len returns the number of keys stored in the tree.
+func (tree *RadixTree[V]) Len() int {
+	return tree.size
+}
+
+// Get returns the value stored at the given key and whether the key was
+// found.
+func (tree *RadixTree[V]) Get(key string) (V, bool) {
+	return tree.root.get(key)
+}
+
+// Returns the value stored at key below node and whether key was found
+func (node *radixNode[V]) get(key string) (V, bool) {
+	for key != "" {
+		i, ok := node.findChild(key[0])
+		if !ok || !strings.HasPrefix(key, node.children[i].prefix) {
+			var zero V
+			return zero, false
+		}
+		node = node.children[i]
+		key = key[len(node.prefix):]
+	}
+	return node.value, node.hasValue
+}
+
+// Lookup returns the value stored at the given key or ErrKeyNotFound if
+// the key is not stored.
+func (tree *RadixTree[V]) Lookup(key string) (V, error) {
+	value, ok := tree.Get(key)
+	if !ok {
+		return value, notFound(key)
+	}
+	return value, nil
+}
+
+// Insert stores value at the given key. Every key can be stored, so it
+// always returns nil.
+func (tree *RadixTree[V]) Insert(key string, value V) error {
+	tree.Put(key, value)
+	return nil
+}
+
+// Put stores value at the given key, splitting the edge where key leaves
+// it. It returns the previous value and whether it was replaced.
+func (tree *RadixTree[V]) Put(key string, value V) (V, bool) {
+	node := tree.root
+	for key != "" {
+		i, ok := node.findChild(key[0])
+		if !ok {
+			node.insertChild(i, &radixNode[V]{prefix: key, value: value, hasValue: true})
+			tree.size += 1
+			var zero V
+			return zero, false
+		}
+
+		child := node.children[i]
+		length := commonPrefixLen(key, child.prefix)
+		if length < len(child.prefix) {
+			// Split the edge at the end of the common prefix
+			mid := &radixNode[V]{
+				prefix:   child.prefix[:length],
+				children: []*radixNode[V]{child},
+			}
+			child.prefix = child.prefix[length:]
+			node.children[i] = mid
+			child = mid
+		}
+
+		node = child
+		key = key[length:]
+	}
+
+	old, replaced := node.value, node.hasValue
+	node.value = value
+	node.hasValue = true
+	if !replaced {
+		tree.size += 1
+	}
+	return old, replaced
+}
+
+// Delete removes the given key, merging nodes left with a single child and
+// no value into it. It returns the removed value and whether the key was
+// found.
+func (tree *RadixTree[V]) Delete(key string) (V, bool) {
+	var zero V
+	var parent *radixNode[V]
+	idx := -1
+	node := tree.root
+	for key != "" {
+		i, ok := node.findChild(key[0])
+		if !ok || !strings.HasPrefix(key, node.children[i].prefix) {
+			return zero, false
+		}
+		parent, idx = node, i
+		node = node.children[i]
+		key = key[len(node.prefix):]
+	}
+
+	if !node.hasValue {
+		return zero, false
+	}
+
+	old := node.value
+	node.value = zero
+	node.hasValue = false
+	tree.size -= 1
+
+	if parent == nil {
+		// The root keeps its place whatever its children
+		return old, true
+	}
+
+	switch len(node.children) {
+	case 0:
+		parent.removeChild(idx)
+		if parent != tree.root && !parent.hasValue && len(parent.children) == 1 {
+			parent.merge()
+		}
+	case 1:
+		node.merge()
+	}
+	return old, true
+}
+
+// WalkPrefix calls fn for every key that starts with prefix, until fn
+// returns false. Keys are visited in ascending byte order.
+func (tree *RadixTree[V]) WalkPrefix(prefix string, fn func(key string, value V) bool) {
+	tree.root.walkPrefix(prefix, fn)
+}
+
+// Visits the keys below node that start with prefix in order
+func (node *radixNode[V]) walkPrefix(prefix string, fn func(key string, value V) bool) {
+	rest := prefix
+	for rest != "" {
+		i, ok := node.findChild(rest[0])
+		if !ok {
+			return
+		}
+		node = node.children[i]
+		if strings.HasPrefix(node.prefix, rest) {
+			// The prefix ends inside the edge of node
+			node.walk(append([]byte(prefix), node.prefix[len(rest):]...), fn)
+			return
+		}
+		if !strings.HasPrefix(rest, node.prefix) {
+			return
+		}
+		rest = rest[len(node.prefix):]
+	}
+	node.walk([]byte(prefix), fn)
+}
+
+// Visits node, whose key is buf, and its descendants in order. Returns
+// false if fn stopped the walk.
+func (node *radixNode[V]) walk(buf []byte, fn func(key string, value V) bool) bool {
+	if node.hasValue && !fn(string(buf), node.value) {
+		return false
+	}
+	for _, child := range node.children {
+		if !child.walk(append(buf, child.prefix...), fn) {
+			return false
+		}
+	}
+	return true
+}
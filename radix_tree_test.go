@@ -0,0 +1,210 @@
+package go_tries
+
+import (
+	mrand "math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestRadixTreeSplitOnPut(t *testing.T) {
+	r := NewRadixTree[int]()
+
+	r.Put("romane", 0)
+	r.Put("romanus", 1)
+	r.Put("romulus", 2)
+
+	if len(r.root.children) != 1 || r.root.children[0].prefix != "rom" {
+		t.Fatalf("expected root to have a single edge %q, got %v", "rom", r.root.children)
+	}
+
+	rom := r.root.children[0]
+	if len(rom.children) != 2 || rom.children[0].prefix != "an" || rom.children[1].prefix != "ulus" {
+		t.Errorf("expected edges %q and %q below %q", "an", "ulus", "rom")
+	}
+
+	for i, key := range []string{"romane", "romanus", "romulus"} {
+		if value, ok := r.Get(key); value != i || ok != true {
+			t.Errorf("expected Get for %v to be %v, got %v", key, i, value)
+		}
+	}
+
+	for _, key := range []string{"", "rom", "roman", "romanes"} {
+		if _, ok := r.Get(key); ok != false {
+			t.Errorf("expected Get for %v to be %v, got %v", key, false, true)
+		}
+	}
+}
+
+func TestRadixTreeMergeOnDelete(t *testing.T) {
+	r := NewRadixTree[int]()
+
+	r.Put("romane", 0)
+	r.Put("romanus", 1)
+	r.Put("romulus", 2)
+
+	if value, ok := r.Delete("romulus"); value != 2 || ok != true {
+		t.Errorf("expected Delete for %v to be %v, got %v", "romulus", 2, value)
+	}
+
+	rom := r.root.children[0]
+	if rom.prefix != "roman" || len(rom.children) != 2 {
+		t.Errorf("expected %q to merge into %q, got %q", "rom", "roman", rom.prefix)
+	}
+
+	r.Delete("romane")
+	if len(r.root.children) != 1 || r.root.children[0].prefix != "romanus" {
+		t.Errorf("expected a single edge %q, got %v", "romanus", r.root.children)
+	}
+
+	if _, ok := r.Delete("roman"); ok != false {
+		t.Errorf("expected Delete for inner node %v to be %v, got %v", "roman", false, true)
+	}
+
+	r.Delete("romanus")
+	if len(r.root.children) != 0 || r.Len() != 0 {
+		t.Errorf("expected empty tree, got %v keys", r.Len())
+	}
+}
+
+func TestRadixTreeEmptyKey(t *testing.T) {
+	r := NewRadixTree[int]()
+
+	r.Put("", 1)
+	r.Put("a", 2)
+
+	if value, ok := r.Get(""); value != 1 || ok != true {
+		t.Errorf("expected Get for %q to be %v, got %v", "", 1, value)
+	}
+
+	if value, ok := r.Delete(""); value != 1 || ok != true {
+		t.Errorf("expected Delete for %q to be %v, got %v", "", 1, value)
+	}
+
+	if value, ok := r.Get("a"); value != 2 || ok != true {
+		t.Errorf("expected Get for %q to be %v, got %v", "a", 2, value)
+	}
+}
+
+func TestRadixTreeWalkPrefix(t *testing.T) {
+	r := NewRadixTree[int]()
+
+	for i, key := range []string{"romane", "romanus", "romulus", "rubens", "ruber", "rom"} {
+		r.Put(key, i)
+	}
+
+	cases := []struct {
+		prefix string
+		keys   []string
+	}{
+		{"rom", []string{"rom", "romane", "romanus", "romulus"}},
+		{"roma", []string{"romane", "romanus"}},
+		{"romanx", nil},
+		{"rube", []string{"rubens", "ruber"}},
+		{"", []string{"rom", "romane", "romanus", "romulus", "rubens", "ruber"}},
+	}
+
+	for _, c := range cases {
+		var keys []string
+		r.WalkPrefix(c.prefix, func(key string, value int) bool {
+			keys = append(keys, key)
+			return true
+		})
+		if !reflect.DeepEqual(keys, c.keys) {
+			t.Errorf("expected WalkPrefix for %q to yield %v, got %v", c.prefix, c.keys, keys)
+		}
+	}
+}
+
+func TestRadixTreeRandomKeys(t *testing.T) {
+	rnd := mrand.New(mrand.NewSource(4))
+	r := NewRadixTree[string]()
+	ref := make(map[string]string)
+
+	for i := 0; i < 5000; i++ {
+		key := string(randomKeyFrom(rnd, "abc", 6))
+		if rnd.Intn(3) == 0 {
+			_, ok := r.Delete(key)
+			if _, exists := ref[key]; ok != exists {
+				t.Fatalf("expected Delete for %q to be %v, got %v", key, exists, ok)
+			}
+			delete(ref, key)
+		} else {
+			r.Put(key, key)
+			ref[key] = key
+		}
+	}
+
+	if r.Len() != len(ref) {
+		t.Errorf("expected Len to be %v, got %v", len(ref), r.Len())
+	}
+
+	var expected []string
+	for key := range ref {
+		expected = append(expected, key)
+		if value, ok := r.Get(key); value != key || ok != true {
+			t.Errorf("expected Get for %q to be %q, got %q", key, key, value)
+		}
+	}
+	sort.Strings(expected)
+
+	var keys []string
+	r.WalkPrefix("", func(key string, value string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected WalkPrefix to yield %v, got %v", expected, keys)
+	}
+}
+
+// Returns a random key of up to max bytes from alphabet
+func randomKeyFrom(r *mrand.Rand, alphabet string, max int) []byte {
+	key := make([]byte, r.Intn(max+1))
+	for i := range key {
+		key[i] = alphabet[r.Intn(len(alphabet))]
+	}
+	return key
+}
+
+func BenchmarkRadixTreePutStringKey(b *testing.B) {
+	trie := NewRadixTree[int]()
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trie.Put(words[i%len(words)], i)
+	}
+}
+
+func BenchmarkRadixTreeGetStringKey(b *testing.B) {
+	trie := NewRadixTree[int]()
+	for i := 0; i < b.N; i++ {
+		trie.Put(words[i%len(words)], i)
+	}
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trie.Get(words[i%len(words)])
+	}
+}
+
+func BenchmarkRadixTreePutPhraseKey(b *testing.B) {
+	trie := NewRadixTree[int]()
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trie.Put(phrases[i%len(phrases)], i)
+	}
+}
+
+func BenchmarkRadixTreeGetPhraseKey(b *testing.B) {
+	trie := NewRadixTree[int]()
+	for i := 0; i < b.N; i++ {
+		trie.Put(phrases[i%len(phrases)], i)
+	}
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trie.Get(phrases[i%len(phrases)])
+	}
+}
@@ -0,0 +1,249 @@
+package go_tries
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// ShardedTrie spreads its keys over several SimpleTries, each with its own
+// lock, so that goroutines writing keys in different shards do not wait for
+// each other. Keys are assigned to a shard by a hash of their first segment
+// or, with WithShardPrefix, of the first bytes of the normalised key. It is
+// safe for concurrent use.
+type ShardedTrie[V any] struct {
+	shards []trieShard[V]
+	// Tokenizer shared by the shards
+	tokenizer Tokenizer
+	// Bytes of the normalised key hashed to pick a shard, 0 for the whole
+	// first segment
+	prefixLen int
+}
+
+type trieShard[V any] struct {
+	mu   sync.RWMutex
+	trie *SimpleTrie[V]
+	// Keeps neighbouring shards on separate cache lines
+	_ [32]byte
+}
+
+// ShardedTrieOption configures a ShardedTrie.
+type ShardedTrieOption func(*shardedTrieOptions)
+
+type shardedTrieOptions struct {
+	shards    int
+	prefixLen int
+	trie      []SimpleTrieOption
+}
+
+// WithShards sets the number of shards. The default is four per
+// GOMAXPROCS.
+func WithShards(n int) ShardedTrieOption {
+	return func(o *shardedTrieOptions) {
+		o.shards = n
+	}
+}
+
+// WithShardPrefix assigns keys to shards by a hash of the first n bytes of
+// the normalised key instead of its first segment. Keys with few segments
+// spread better this way, but only prefix walks of at least n bytes stay
+// in a single shard.
+func WithShardPrefix(n int) ShardedTrieOption {
+	return func(o *shardedTrieOptions) {
+		o.prefixLen = n
+	}
+}
+
+// WithShardOptions configures the SimpleTrie of every shard, for example to
+// set its tokenizer.
+func WithShardOptions(options ...SimpleTrieOption) ShardedTrieOption {
+	return func(o *shardedTrieOptions) {
+		o.trie = append(o.trie, options...)
+	}
+}
+
+// NewShardedTrie allocates and returns a new *ShardedTrie.
+func NewShardedTrie[V any](options ...ShardedTrieOption) *ShardedTrie[V] {
+	o := shardedTrieOptions{shards: 4 * runtime.GOMAXPROCS(0)}
+	for _, option := range options {
+		option(&o)
+	}
+
+	s := &ShardedTrie[V]{
+		shards:    make([]trieShard[V], max(o.shards, 1)),
+		prefixLen: max(o.prefixLen, 0),
+	}
+	for i := range s.shards {
+		s.shards[i].trie = NewSimpleTrie[V](o.trie...)
+	}
+	s.tokenizer = s.shards[0].trie.tokens()
+	return s
+}
+
+// Adds the first n bytes of str to the FNV-1a hash h. Returns the new hash
+// and how many of the n bytes are left.
+func hashPrefix(h uint32, str string, n int) (uint32, int) {
+	for i := 0; i < len(str) && n > 0; i++ {
+		h ^= uint32(str[i])
+		h *= 16777619
+		n -= 1
+	}
+	return h, n
+}
+
+// Returns the index of the shard holding key and whether every key that
+// starts with the words of key lives in the same shard.
+func (s *ShardedTrie[V]) shardOf(key string) (int, bool) {
+	h := uint32(2166136261)
+	tokens := s.tokenizer
+	first, rest := tokens.Next(key)
+	full := key != ""
+	if s.prefixLen == 0 {
+		h, _ = hashPrefix(h, first, len(first))
+	} else {
+		// Hash the key as SimpleTrie reports it, so that keys stored at
+		// the same node share a shard
+		left := s.prefixLen
+		h, left = hashPrefix(h, first, left)
+		for part := ""; rest != "" && left > 0; {
+			part, rest = tokens.Next(rest)
+			h, left = hashPrefix(h, tokens.Separator(), left)
+			h, left = hashPrefix(h, part, left)
+		}
+		full = left == 0
+	}
+	return int(h % uint32(len(s.shards))), full
+}
+
+// Len returns the number of keys stored in the trie. Shards are counted one
+// after the other, so concurrent writes may or may not be included.
+func (s *ShardedTrie[V]) Len() int {
+	n := 0
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.RLock()
+		n += shard.trie.Len()
+		shard.mu.RUnlock()
+	}
+	return n
+}
+
+// Get returns the value stored at the given key and whether the key was
+// found.
+func (s *ShardedTrie[V]) Get(key string) (V, bool) {
+	i, _ := s.shardOf(key)
+	shard := &s.shards[i]
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	return shard.trie.Get(key)
+}
+
+// Lookup returns the value stored at the given key or ErrKeyNotFound if
+// the key is not stored.
+func (s *ShardedTrie[V]) Lookup(key string) (V, error) {
+	value, ok := s.Get(key)
+	if !ok {
+		return value, notFound(key)
+	}
+	return value, nil
+}
+
+// Insert stores value at the given key. Every key can be stored, so it
+// always returns nil.
+func (s *ShardedTrie[V]) Insert(key string, value V) error {
+	s.Put(key, value)
+	return nil
+}
+
+// Put stores value at the given key. It returns the previous value and
+// whether it was replaced.
+func (s *ShardedTrie[V]) Put(key string, value V) (V, bool) {
+	i, _ := s.shardOf(key)
+	shard := &s.shards[i]
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	return shard.trie.Put(key, value)
+}
+
+// Delete removes the given key. It returns the removed value and whether
+// the key was found.
+func (s *ShardedTrie[V]) Delete(key string) (V, bool) {
+	i, _ := s.shardOf(key)
+	shard := &s.shards[i]
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	return shard.trie.Delete(key)
+}
+
+// A key and value copied out of a shard
+type shardEntry[V any] struct {
+	key   string
+	value V
+}
+
+// WalkPrefix calls fn for every key that starts with the words of prefix,
+// until fn returns false. Keys of all shards are merged into the word order
+// of SimpleTrie.WalkPrefix.
+//
+// The keys of each shard are copied under its read lock and fn is called
+// without holding any lock, so fn may modify the trie. Shards are read one
+// after the other, so the walk sees each shard at a different time.
+func (s *ShardedTrie[V]) WalkPrefix(prefix string, fn func(key string, value V) bool) {
+	first, last := 0, len(s.shards)
+	if i, full := s.shardOf(prefix); full {
+		first, last = i, i+1
+	}
+
+	var runs [][]shardEntry[V]
+	for i := first; i < last; i++ {
+		var run []shardEntry[V]
+		shard := &s.shards[i]
+		shard.mu.RLock()
+		shard.trie.WalkPrefix(prefix, func(key string, value V) bool {
+			run = append(run, shardEntry[V]{key: key, value: value})
+			return true
+		})
+		shard.mu.RUnlock()
+		if len(run) > 0 {
+			runs = append(runs, run)
+		}
+	}
+
+	// Merge the runs, each already in word order
+	for len(runs) > 0 {
+		least := 0
+		for i := 1; i < len(runs); i++ {
+			if compareWords(s.tokenizer, runs[i][0].key, runs[least][0].key) < 0 {
+				least = i
+			}
+		}
+		entry := runs[least][0]
+		if runs[least] = runs[least][1:]; len(runs[least]) == 0 {
+			runs = append(runs[:least], runs[least+1:]...)
+		}
+		if !fn(entry.key, entry.value) {
+			return
+		}
+	}
+}
+
+// Compares two keys segment by segment, so that a key sorts before the
+// keys it is a prefix of.
+func compareWords(tokens Tokenizer, a, b string) int {
+	for {
+		partA, restA := tokens.Next(a)
+		partB, restB := tokens.Next(b)
+		if c := strings.Compare(partA, partB); c != 0 {
+			return c
+		}
+		switch {
+		case restA == "" && restB == "":
+			return 0
+		case restA == "":
+			return -1
+		case restB == "":
+			return 1
+		}
+		a, b = restA, restB
+	}
+}
@@ -0,0 +1,138 @@
+package go_tries
+
+import (
+	"fmt"
+	mrand "math/rand"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// Returns the keys and values below prefix in walk order
+func walkPairs(trie interface {
+	WalkPrefix(string, func(string, int) bool)
+}, prefix string) []string {
+	var pairs []string
+	trie.WalkPrefix(prefix, func(key string, value int) bool {
+		pairs = append(pairs, fmt.Sprintf("%q=%d", key, value))
+		return true
+	})
+	return pairs
+}
+
+func TestShardedTrieMatchesSimpleTrie(t *testing.T) {
+	cases := map[string][]ShardedTrieOption{
+		"FirstSegment": {WithShards(7)},
+		"Prefix":       {WithShards(7), WithShardPrefix(3)},
+		"Bytes":        {WithShards(5), WithShardPrefix(2), WithShardOptions(WithTokenizer(ByteTokenizer{}))},
+	}
+	for name, options := range cases {
+		rnd := mrand.New(mrand.NewSource(20))
+		s := NewShardedTrie[int](options...)
+		ref := NewSimpleTrie[int](WithTokenizer(s.tokenizer))
+
+		for i := 0; i < 5000; i++ {
+			key := string(randomKeyFrom(rnd, "ab c", 8))
+			if rnd.Intn(3) == 0 {
+				old, ok := s.Delete(key)
+				expected, exists := ref.Delete(key)
+				if ok != exists || old != expected {
+					t.Fatalf("%s: expected Delete for %q to be (%v, %v), got (%v, %v)", name, key, expected, exists, old, ok)
+				}
+			} else {
+				old, replaced := s.Put(key, i)
+				expected, exists := ref.Put(key, i)
+				if replaced != exists || old != expected {
+					t.Fatalf("%s: expected Put for %q to be (%v, %v), got (%v, %v)", name, key, expected, exists, old, replaced)
+				}
+			}
+		}
+
+		if s.Len() != ref.Len() {
+			t.Errorf("%s: expected Len to be %v, got %v", name, ref.Len(), s.Len())
+		}
+		for _, prefix := range []string{"", "a", "ab", "a b", "c", "abc", " ", "ba c"} {
+			expected := walkPairs(ref, prefix)
+			if pairs := walkPairs(s, prefix); !reflect.DeepEqual(pairs, expected) {
+				t.Errorf("%s: expected WalkPrefix for %q to yield %v, got %v", name, prefix, expected, pairs)
+			}
+		}
+	}
+}
+
+func TestShardedTrieWalkPrefixStops(t *testing.T) {
+	s := NewShardedTrie[int](WithShards(4))
+	for i := 0; i < 100; i++ {
+		s.Put(fmt.Sprintf("key %02d", i), i)
+	}
+
+	var keys []string
+	s.WalkPrefix("", func(key string, value int) bool {
+		keys = append(keys, key)
+		return len(keys) < 3
+	})
+	expected := []string{"key 00", "key 01", "key 02"}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected WalkPrefix to yield %v, got %v", expected, keys)
+	}
+}
+
+func TestShardedTrieConcurrent(t *testing.T) {
+	const goroutines = 8
+	const keys = 500
+	s := NewShardedTrie[int]()
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < keys; i++ {
+				key := fmt.Sprintf("g%d key %d", g, i)
+				s.Put(key, i)
+				s.Get(fmt.Sprintf("g%d key %d", (g+1)%goroutines, i))
+				if i%2 == 1 {
+					s.Delete(key)
+				}
+				if i%100 == 0 {
+					s.WalkPrefix(fmt.Sprintf("g%d", g), func(string, int) bool { return true })
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if s.Len() != goroutines*keys/2 {
+		t.Errorf("expected Len to be %v, got %v", goroutines*keys/2, s.Len())
+	}
+}
+
+// The PutParallel benchmarks show how inserts scale with GOMAXPROCS when
+// run with -cpu, for example
+//
+//	go test -run NONE -bench PutParallel -cpu 1,2,4,8
+
+// Puts keys from every goroutine, each starting at a random word
+func benchmarkPutParallel(b *testing.B, trie Trie[int]) {
+	b.ResetTimer()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := mrand.Intn(len(words))
+		for pb.Next() {
+			trie.Put(words[i%len(words)], i)
+			i++
+		}
+	})
+}
+
+func BenchmarkShardedTriePutParallel(b *testing.B) {
+	benchmarkPutParallel(b, NewShardedTrie[int]())
+}
+
+func BenchmarkShardedTriePutParallelPrefix(b *testing.B) {
+	benchmarkPutParallel(b, NewShardedTrie[int](WithShardPrefix(2)))
+}
+
+func BenchmarkConcurrentSimpleTriePutParallel(b *testing.B) {
+	benchmarkPutParallel(b, Concurrent[int](NewSimpleTrie[int]()))
+}
@@ -1,44 +1,152 @@
 package go_tries
 
-type SimpleTrie struct {
+import "sort"
+
+type SimpleTrie[V any] struct {
+	// Root node
+	root *simpleNode[V]
+	// Number of keys stored
+	size int
+	// Codec of the values when the trie is encoded
+	codec ValueCodec[V]
+	// Splits keys into the parts stored at each level
+	tokenizer Tokenizer
+}
+
+type simpleNode[V any] struct {
 	// Reference to children
-	children map[string]*SimpleTrie
+	children map[string]*simpleNode[V]
+	// Parts of the children in ascending order
+	parts []string
 	// Value of Node
-	value interface{}
+	value V
+	// Whether the node holds a value
+	hasValue bool
 }
 
-// NewSimpleTrie allocates and returns a new *SimpleTrie.
-func NewSimpleTrie() *SimpleTrie {
-	return &SimpleTrie{
-		children: make(map[string]*SimpleTrie),
+func newSimpleNode[V any]() *simpleNode[V] {
+	return &simpleNode[V]{
+		children: make(map[string]*simpleNode[V]),
 	}
 }
 
-// Get returns the value stored at the given key. Returns nil for internal
-// nodes or for nodes with a value of nil.
-func (trie *SimpleTrie) Get(key string) interface{} {
-	node := trie
-	for part, rest := SplitPath(key, " "); ; part, rest = SplitPath(rest, " "){
-	    node = node.children[part]
+// Adds child under part keeping parts sorted
+func (node *simpleNode[V]) addChild(part string, child *simpleNode[V]) {
+	i := sort.SearchStrings(node.parts, part)
+	node.parts = append(node.parts, "")
+	copy(node.parts[i+1:], node.parts[i:])
+	node.parts[i] = part
+	node.children[part] = child
+}
+
+// Removes the child under part
+func (node *simpleNode[V]) removeChild(part string) {
+	i := sort.SearchStrings(node.parts, part)
+	node.parts = append(node.parts[:i], node.parts[i+1:]...)
+	delete(node.children, part)
+}
+
+// Returns the child at index i of parts
+func (node *simpleNode[V]) child(i int) *simpleNode[V] {
+	return node.children[node.parts[i]]
+}
+
+// Splits keys on spaces unless another tokenizer is set
+var defaultTokenizer = SeparatorTokenizer(" ")
+
+// SimpleTrieOption configures a SimpleTrie.
+type SimpleTrieOption func(*simpleTrieOptions)
+
+type simpleTrieOptions struct {
+	tokenizer Tokenizer
+}
+
+// WithTokenizer makes the trie split keys with t.
+func WithTokenizer(t Tokenizer) SimpleTrieOption {
+	return func(o *simpleTrieOptions) {
+		o.tokenizer = t
+	}
+}
+
+// WithSeparator makes the trie split keys on sep instead of spaces.
+func WithSeparator(sep string) SimpleTrieOption {
+	return WithTokenizer(SeparatorTokenizer(sep))
+}
+
+// NewSimpleTrie allocates and returns a new *SimpleTrie. Keys are split
+// into words on spaces unless an option sets another tokenizer.
+func NewSimpleTrie[V any](options ...SimpleTrieOption) *SimpleTrie[V] {
+	o := simpleTrieOptions{tokenizer: defaultTokenizer}
+	for _, option := range options {
+		option(&o)
+	}
+	return &SimpleTrie[V]{
+		root:      newSimpleNode[V](),
+		tokenizer: o.tokenizer,
+	}
+}
+
+// Returns the tokenizer of the trie. A zero SimpleTrie, which decoders
+// create, splits on spaces.
+func (trie *SimpleTrie[V]) tokens() Tokenizer {
+	if trie.tokenizer == nil {
+		return defaultTokenizer
+	}
+	return trie.tokenizer
+}
+
+// Len returns the number of keys stored in the trie.
+func (trie *SimpleTrie[V]) Len() int {
+	return trie.size
+}
+
+// Get returns the value stored at the given key and whether the key was
+// found. Internal nodes are reported as not found.
+func (trie *SimpleTrie[V]) Get(key string) (V, bool) {
+	node := trie.root
+	tokens := trie.tokens()
+	for part, rest := tokens.Next(key); ; part, rest = tokens.Next(rest) {
+		node = node.children[part]
 		if node == nil {
-			return nil
+			var zero V
+			return zero, false
 		}
 		if rest == "" {
 			break
 		}
 
 	}
-	return node.value
+	return node.value, node.hasValue
+}
+
+// Lookup returns the value stored at the given key or ErrKeyNotFound if
+// the key is not stored.
+func (trie *SimpleTrie[V]) Lookup(key string) (V, error) {
+	value, ok := trie.Get(key)
+	if !ok {
+		return value, notFound(key)
+	}
+	return value, nil
 }
 
-func (trie *SimpleTrie) Add(key string, value int) bool {
-	node := trie
-	for part, rest := SplitPath(key, " "); ; part, rest = SplitPath(rest, " "){
+// Insert stores value at the given key. Every key can be stored, so it
+// always returns nil.
+func (trie *SimpleTrie[V]) Insert(key string, value V) error {
+	trie.Put(key, value)
+	return nil
+}
+
+// Put stores value at the given key. It returns the previous value and
+// whether it was replaced.
+func (trie *SimpleTrie[V]) Put(key string, value V) (V, bool) {
+	node := trie.root
+	tokens := trie.tokens()
+	for part, rest := tokens.Next(key); ; part, rest = tokens.Next(rest) {
 		child, _ := node.children[part]
 
 		if child == nil {
-			child = NewSimpleTrie()
-			node.children[part] = child
+			child = newSimpleNode[V]()
+			node.addChild(part, child)
 		}
 
 		node = child
@@ -48,35 +156,51 @@ func (trie *SimpleTrie) Add(key string, value int) bool {
 
 	}
 
-	isNewVal := node.value == nil
+	old, replaced := node.value, node.hasValue
 	node.value = value
+	node.hasValue = true
+	if !replaced {
+		trie.size += 1
+	}
 
-	return isNewVal
+	return old, replaced
 }
 
 // PathTrie node and the part string key of the child the path descends into.
-type nodeStr struct {
-	node *SimpleTrie
+type nodeStr[V any] struct {
+	node *simpleNode[V]
 	part string
 }
 
-func (trie *SimpleTrie) Delete(key string) bool {
-	var path []nodeStr // record ancestors to check later
-	node := trie
-	for part, rest := SplitPath(key, " "); ; part, rest = SplitPath(rest, " "){
-	    path = append(path, nodeStr{part: part, node: node})
+// Delete removes the value at the given key together with any ancestors
+// left without values or children. It returns the removed value and
+// whether the key was found.
+func (trie *SimpleTrie[V]) Delete(key string) (V, bool) {
+	var zero V
+	var path []nodeStr[V] // record ancestors to check later
+	node := trie.root
+	tokens := trie.tokens()
+	for part, rest := tokens.Next(key); ; part, rest = tokens.Next(rest) {
+		path = append(path, nodeStr[V]{part: part, node: node})
 		node = node.children[part]
 		if node == nil {
 			// node does not exist
-			return false
+			return zero, false
 		}
 		if rest == "" {
 			break
 		}
 	}
 
+	if !node.hasValue {
+		return zero, false
+	}
+
 	// delete the node value
-	node.value = nil
+	old := node.value
+	node.value = zero
+	node.hasValue = false
+	trie.size -= 1
 
 	// if leaf, remove it from its parent's children map. Repeat for ancestor path.
 	if len(node.children) == 0 {
@@ -84,13 +208,232 @@ func (trie *SimpleTrie) Delete(key string) bool {
 		for i := len(path) - 1; i >= 0; i-- {
 			parent := path[i].node
 			part := path[i].part
-			delete(parent.children, part)
-			if parent.value != nil || !(len(parent.children) == 0) {
+			parent.removeChild(part)
+			if parent.hasValue || !(len(parent.children) == 0) {
 				// parent has a value or has other children, stop
 				break
 			}
 		}
 	}
 
+	return old, true
+}
+
+// WalkPrefix calls fn for every key that starts with the words of prefix,
+// until fn returns false.
+//
+// Keys are visited in word order rather than byte order: a key is visited
+// before the keys it is a prefix of and the children of a node are visited
+// in ascending word order, so "dog" yields "dog", "dog and" and
+// "dog and cat". Keys are reported normalised, with their words joined by
+// the separator of the tokenizer, so with the default tokenizer "dog " and
+// " dog" are both reported as "dog".
+func (trie *SimpleTrie[V]) WalkPrefix(prefix string, fn func(key string, value V) bool) {
+	node := trie.root
+	tokens := trie.tokens()
+	var key []byte
+	if prefix != "" {
+		for part, rest := tokens.Next(prefix); ; part, rest = tokens.Next(rest) {
+			node = node.children[part]
+			if node == nil {
+				return
+			}
+			key = appendPart(key, tokens.Separator(), part)
+			if rest == "" {
+				break
+			}
+		}
+	}
+	node.walk(key, tokens.Separator(), fn)
+}
+
+// Appends a word to a normalised key, after sep unless it is the first
+func appendPart(key []byte, sep string, part string) []byte {
+	if len(key) > 0 {
+		key = append(key, sep...)
+	}
+	return append(key, part...)
+}
+
+// Visits node, whose normalised key is key, and its descendants in order.
+// Parts are joined by sep. Returns false if fn stopped the walk.
+func (node *simpleNode[V]) walk(key []byte, sep string, fn func(key string, value V) bool) bool {
+	if node.hasValue && !fn(string(key), node.value) {
+		return false
+	}
+	for _, part := range node.parts {
+		if !node.children[part].walk(appendPart(key, sep, part), sep, fn) {
+			return false
+		}
+	}
 	return true
 }
+
+// LongestPrefix returns the longest stored key made of whole leading words
+// of key, together with its value. The matched key is a prefix of key. It
+// does not allocate.
+func (trie *SimpleTrie[V]) LongestPrefix(key string) (string, V, bool) {
+	var value V
+	length := -1
+	node := trie.root
+	tokens := trie.tokens()
+	for part, rest := tokens.Next(key); ; part, rest = tokens.Next(rest) {
+		node = node.children[part]
+		if node == nil {
+			break
+		}
+		if node.hasValue {
+			length, value = len(key)-len(rest), node.value
+		}
+		if rest == "" {
+			break
+		}
+	}
+	if length == -1 {
+		return "", value, false
+	}
+	return key[:length], value, true
+}
+
+// Returns an iterator positioned before the first key. Keys are ordered and
+// normalised as in WalkPrefix, so the order is word order, not byte order.
+func (trie *SimpleTrie[V]) Iterator() *SimpleTrieIterator[V] {
+	return &SimpleTrieIterator[V]{
+		root:  trie.root,
+		sep:   trie.tokens().Separator(),
+		stack: []simpleFrame[V]{{node: trie.root, i: -1}},
+	}
+}
+
+// Returns an iterator positioned before the first key that is not less
+// than key, so that Next moves to that key and Prev to the key before it.
+func (trie *SimpleTrie[V]) Seek(key string) *SimpleTrieIterator[V] {
+	it := trie.Iterator()
+	it.between = true
+	tokens := trie.tokens()
+	for part, rest := tokens.Next(key); ; part, rest = tokens.Next(rest) {
+		top := &it.stack[len(it.stack)-1]
+		j := sort.SearchStrings(top.node.parts, part)
+		if j == len(top.node.parts) || top.node.parts[j] != part || rest == "" {
+			top.i = j - 1
+			return it
+		}
+		top.i = j
+		it.stack = append(it.stack, simpleFrame[V]{node: top.node.child(j), i: -1})
+	}
+}
+
+// A node on the path of an iterator and the index into its parts of the
+// child the path descends into, -1 when the iterator is at the node.
+type simpleFrame[V any] struct {
+	node *simpleNode[V]
+	i    int
+}
+
+// SimpleTrieIterator is a cursor over the keys of a SimpleTrie. It must not
+// be used after the trie is modified.
+type SimpleTrieIterator[V any] struct {
+	root *simpleNode[V]
+	// Separator joining the parts of keys
+	sep   string
+	stack []simpleFrame[V]
+	// Set after a Seek until the cursor moves
+	between bool
+}
+
+// Returns the node the iterator is at or nil
+func (it *SimpleTrieIterator[V]) current() *simpleNode[V] {
+	if it.between || len(it.stack) == 0 {
+		return nil
+	}
+	top := it.stack[len(it.stack)-1]
+	if top.i != -1 || !top.node.hasValue {
+		return nil
+	}
+	return top.node
+}
+
+// Next moves to the next key and reports whether there is one.
+func (it *SimpleTrieIterator[V]) Next() bool {
+	it.between = false
+	for len(it.stack) > 0 {
+		top := &it.stack[len(it.stack)-1]
+		top.i += 1
+		if top.i < len(top.node.parts) {
+			child := top.node.child(top.i)
+			it.stack = append(it.stack, simpleFrame[V]{node: child, i: -1})
+			if child.hasValue {
+				return true
+			}
+			continue
+		}
+		it.stack = it.stack[:len(it.stack)-1]
+	}
+	return false
+}
+
+// Prev moves to the previous key and reports whether there is one.
+func (it *SimpleTrieIterator[V]) Prev() bool {
+	if len(it.stack) == 0 {
+		// Past the end, step back from behind the last child of root
+		it.stack = append(it.stack, simpleFrame[V]{node: it.root, i: len(it.root.parts)})
+	} else if it.between {
+		// Seek left the path at the child before the sought key
+		it.stack[len(it.stack)-1].i += 1
+	}
+	it.between = false
+
+	for {
+		top := &it.stack[len(it.stack)-1]
+		if top.i == -1 {
+			if len(it.stack) == 1 {
+				return false
+			}
+			it.stack = it.stack[:len(it.stack)-1]
+			continue
+		}
+
+		top.i -= 1
+		if top.i == -1 {
+			if top.node.hasValue {
+				return true
+			}
+			continue
+		}
+
+		// Descend to the last node in the subtree of the previous child
+		node := top.node.child(top.i)
+		for len(node.parts) > 0 {
+			it.stack = append(it.stack, simpleFrame[V]{node: node, i: len(node.parts) - 1})
+			node = node.child(len(node.parts) - 1)
+		}
+		it.stack = append(it.stack, simpleFrame[V]{node: node, i: -1})
+		if node.hasValue {
+			return true
+		}
+	}
+}
+
+// Key returns the normalised key at the iterator or "" if it is not at a
+// key.
+func (it *SimpleTrieIterator[V]) Key() string {
+	if it.current() == nil {
+		return ""
+	}
+
+	var key []byte
+	for _, frame := range it.stack[:len(it.stack)-1] {
+		key = appendPart(key, it.sep, frame.node.parts[frame.i])
+	}
+	return string(key)
+}
+
+// Value returns the value at the iterator or the zero value if it is not
+// at a key.
+func (it *SimpleTrieIterator[V]) Value() V {
+	if node := it.current(); node != nil {
+		return node.value
+	}
+	var zero V
+	return zero
+}
@@ -0,0 +1,271 @@
+package go_tries
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+)
+
+// Binary SimpleTrie. Integers are unsigned varints unless noted.
+//
+//	magic    [4]byte  "GTST"
+//	version  byte
+//	size     number of keys
+//	nodes    the nodes in preorder, starting at the root
+//	valueLen length of the encoded values in bytes
+//	values   [valueLen]byte, the values in preorder
+//	checksum uint32   little endian CRC-32 (IEEE) of everything before it
+//
+// Every node is the number of its children shifted left by one, with the
+// lowest bit set when it holds a value, followed by each child as the
+// length and bytes of its part and the child node, in ascending part order.
+const (
+	stMagic   = "GTST"
+	stVersion = 1
+)
+
+// SetValueCodec sets the codec MarshalBinary and UnmarshalBinary use for
+// values. The default is GobCodec.
+func (trie *SimpleTrie[V]) SetValueCodec(codec ValueCodec[V]) {
+	trie.codec = codec
+}
+
+func (trie *SimpleTrie[V]) valueCodec() ValueCodec[V] {
+	if trie.codec == nil {
+		return GobCodec[V]{}
+	}
+	return trie.codec
+}
+
+// MarshalBinary encodes the trie in a compact preorder form. Values are
+// encoded with the codec set by SetValueCodec. Since SimpleTrie implements
+// encoding.BinaryMarshaler, encoding/gob uses it too. The tokenizer is not
+// encoded, so decode into a trie splitting keys the same way.
+func (trie *SimpleTrie[V]) MarshalBinary() ([]byte, error) {
+	buf := append([]byte(stMagic), stVersion)
+	buf = binary.AppendUvarint(buf, uint64(trie.size))
+
+	var values []V
+	buf = trie.root.appendBinary(buf, &values)
+
+	var encoded bytes.Buffer
+	if err := trie.valueCodec().EncodeValues(&encoded, values); err != nil {
+		return nil, fmt.Errorf("go_tries: encoding values: %w", err)
+	}
+	buf = binary.AppendUvarint(buf, uint64(encoded.Len()))
+	buf = append(buf, encoded.Bytes()...)
+	return binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf)), nil
+}
+
+// Appends node and its descendants to buf and their values to values
+func (node *simpleNode[V]) appendBinary(buf []byte, values *[]V) []byte {
+	header := uint64(len(node.parts)) << 1
+	if node.hasValue {
+		header |= 1
+		*values = append(*values, node.value)
+	}
+	buf = binary.AppendUvarint(buf, header)
+
+	for _, part := range node.parts {
+		buf = binary.AppendUvarint(buf, uint64(len(part)))
+		buf = append(buf, part...)
+		buf = node.children[part].appendBinary(buf, values)
+	}
+	return buf
+}
+
+// Reads the encoding of a SimpleTrie
+type stDecoder struct {
+	data []byte
+	// Number of nodes holding a value
+	values int
+}
+
+func (dec *stDecoder) uvarint() (uint64, error) {
+	x, n := binary.Uvarint(dec.data)
+	if n == 0 {
+		return 0, ErrTruncated
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("%w: bad varint", ErrCorrupt)
+	}
+	dec.data = dec.data[n:]
+	return x, nil
+}
+
+func (dec *stDecoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(dec.data)) {
+		return nil, ErrTruncated
+	}
+	b := dec.data[:n]
+	dec.data = dec.data[n:]
+	return b, nil
+}
+
+// Decodes a node and its descendants. Every node but the root has to
+// hold a value or children, as Delete leaves no other nodes behind, and
+// the root never holds a value.
+func decodeSimpleNode[V any](dec *stDecoder, root bool) (*simpleNode[V], error) {
+	header, err := dec.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if header == 0 && !root {
+		return nil, fmt.Errorf("%w: empty node", ErrCorrupt)
+	}
+	if header&1 != 0 && root {
+		return nil, fmt.Errorf("%w: value at the root", ErrCorrupt)
+	}
+	// Every child takes at least two bytes
+	children := header >> 1
+	if children > uint64(len(dec.data)/2) {
+		return nil, ErrTruncated
+	}
+
+	node := newSimpleNode[V]()
+	if header&1 != 0 {
+		node.hasValue = true
+		dec.values++
+	}
+	for i := uint64(0); i < children; i++ {
+		length, err := dec.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		part, err := dec.bytes(length)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 && node.parts[i-1] >= string(part) {
+			return nil, fmt.Errorf("%w: part %q out of order", ErrCorrupt, part)
+		}
+
+		child, err := decodeSimpleNode[V](dec, false)
+		if err != nil {
+			return nil, err
+		}
+		node.parts = append(node.parts, string(part))
+		node.children[string(part)] = child
+	}
+	return node, nil
+}
+
+// UnmarshalBinary replaces the contents of the trie with data encoded by
+// MarshalBinary. Data that ends early, fails its checksum or does not
+// describe a valid trie is rejected with an error wrapping ErrCorrupt and
+// leaves the trie unchanged.
+func (trie *SimpleTrie[V]) UnmarshalBinary(data []byte) error {
+	if len(data) < len(stMagic)+1+4 {
+		return ErrTruncated
+	}
+	if string(data[:len(stMagic)]) != stMagic {
+		return ErrBadMagic
+	}
+	if version := data[len(stMagic)]; version != stVersion {
+		return fmt.Errorf("%w %d", ErrVersion, version)
+	}
+	end := len(data) - 4
+	if crc32.ChecksumIEEE(data[:end]) != binary.LittleEndian.Uint32(data[end:]) {
+		return ErrChecksum
+	}
+
+	dec := &stDecoder{data: data[len(stMagic)+1 : end]}
+	size, err := dec.uvarint()
+	if err != nil {
+		return err
+	}
+	root, err := decodeSimpleNode[V](dec, true)
+	if err != nil {
+		return err
+	}
+	if uint64(dec.values) != size {
+		return fmt.Errorf("%w: %d values for %d keys", ErrCorrupt, dec.values, size)
+	}
+
+	length, err := dec.uvarint()
+	if err != nil {
+		return err
+	}
+	encoded, err := dec.bytes(length)
+	if err != nil {
+		return err
+	}
+	if len(dec.data) != 0 {
+		return fmt.Errorf("%w: %d trailing bytes", ErrCorrupt, len(dec.data))
+	}
+
+	values, err := trie.valueCodec().DecodeValues(bytes.NewReader(encoded), dec.values)
+	if err != nil {
+		return fmt.Errorf("%w: decoding values: %v", ErrCorrupt, err)
+	}
+	root.setValues(&values)
+
+	trie.root = root
+	trie.size = int(size)
+	return nil
+}
+
+// Assigns values in preorder to node and its descendants that hold one
+func (node *simpleNode[V]) setValues(values *[]V) {
+	if node.hasValue {
+		node.value = (*values)[0]
+		*values = (*values)[1:]
+	}
+	for _, part := range node.parts {
+		node.children[part].setValues(values)
+	}
+}
+
+// MarshalJSON encodes the trie as nested objects keyed by segment. Every node
+// is an object with its value under "value", when it holds one, and its
+// children under "children", so
+//
+//	{"dog": {"value": 2, "children": {"and": {"value": 3}}}}
+//
+// holds "dog" and "dog and". Values are encoded with encoding/json.
+func (trie *SimpleTrie[V]) MarshalJSON() ([]byte, error) {
+	return trie.root.appendJSONChildren(nil)
+}
+
+// Appends the object holding the children of node
+func (node *simpleNode[V]) appendJSONChildren(buf []byte) ([]byte, error) {
+	buf = append(buf, '{')
+	for i, part := range node.parts {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		key, err := json.Marshal(part)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(append(buf, key...), ':')
+		if buf, err = node.children[part].appendJSON(buf); err != nil {
+			return nil, err
+		}
+	}
+	return append(buf, '}'), nil
+}
+
+// Appends the object of node
+func (node *simpleNode[V]) appendJSON(buf []byte) ([]byte, error) {
+	buf = append(buf, '{')
+	if node.hasValue {
+		value, err := json.Marshal(node.value)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(append(buf, `"value":`...), value...)
+	}
+	if len(node.parts) > 0 {
+		if node.hasValue {
+			buf = append(buf, ',')
+		}
+		var err error
+		if buf, err = node.appendJSONChildren(append(buf, `"children":`...)); err != nil {
+			return nil, err
+		}
+	}
+	return append(buf, '}'), nil
+}
@@ -0,0 +1,152 @@
+package go_tries
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// Returns the keys and values of trie in order
+func simpleEntries[V any](trie *SimpleTrie[V]) ([]string, []V) {
+	var keys []string
+	var values []V
+	trie.WalkPrefix("", func(key string, value V) bool {
+		keys = append(keys, key)
+		values = append(values, value)
+		return true
+	})
+	return keys, values
+}
+
+func TestSimpleTrieMarshalBinary(t *testing.T) {
+	trie := NewSimpleTrie[int]()
+	for i, phrase := range phrases[:200] {
+		trie.Put(phrase, i)
+	}
+	trie.Put("", -1)
+	trie.Put("dog and cat", -2)
+	trie.Delete(phrases[10])
+
+	data, err := trie.MarshalBinary()
+	if err != nil {
+		t.Fatalf("expected MarshalBinary to succeed, got %v", err)
+	}
+
+	decoded := NewSimpleTrie[int]()
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("expected UnmarshalBinary to succeed, got %v", err)
+	}
+
+	if decoded.Len() != trie.Len() {
+		t.Errorf("expected Len to be %v, got %v", trie.Len(), decoded.Len())
+	}
+	expectedKeys, expectedValues := simpleEntries(trie)
+	keys, values := simpleEntries(decoded)
+	if !reflect.DeepEqual(keys, expectedKeys) || !reflect.DeepEqual(values, expectedValues) {
+		t.Fatalf("expected the decoded trie to hold the encoded keys and values")
+	}
+
+	// Deleting from the decoded trie prunes as usual
+	decoded.Delete("dog and cat")
+	if _, ok := decoded.Get("dog and cat"); ok {
+		t.Errorf("expected Get for %q to be %v, got %v", "dog and cat", false, ok)
+	}
+}
+
+func TestSimpleTrieUnmarshalBinaryCorrupt(t *testing.T) {
+	trie := NewSimpleTrie[string]()
+	trie.Put("dog", "d")
+	trie.Put("dog and cat", "c")
+	data, err := trie.MarshalBinary()
+	if err != nil {
+		t.Fatalf("expected MarshalBinary to succeed, got %v", err)
+	}
+
+	for n := 0; n < len(data); n++ {
+		if err := NewSimpleTrie[string]().UnmarshalBinary(data[:n]); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("expected UnmarshalBinary of %v bytes to fail with %v, got %v", n, ErrCorrupt, err)
+		}
+	}
+
+	for i := range data {
+		corrupt := append([]byte(nil), data...)
+		corrupt[i] ^= 0x40
+		decoded := NewSimpleTrie[string]()
+		decoded.Put("kept", "k")
+		if err := decoded.UnmarshalBinary(corrupt); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("expected UnmarshalBinary with byte %v flipped to fail with %v, got %v", i, ErrCorrupt, err)
+		}
+		if value, _ := decoded.Get("kept"); value != "k" {
+			t.Fatalf("expected a failed UnmarshalBinary to leave the trie unchanged")
+		}
+	}
+}
+
+func TestSimpleTrieGob(t *testing.T) {
+	trie := NewSimpleTrie[[]string]()
+	trie.Put("dog", []string{"canine"})
+	trie.Put("dog and cat", []string{"pets", "animals"})
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(trie); err != nil {
+		t.Fatalf("expected gob to encode the trie, got %v", err)
+	}
+	var decoded SimpleTrie[[]string]
+	if err := gob.NewDecoder(&buf).Decode(&decoded); err != nil {
+		t.Fatalf("expected gob to decode the trie, got %v", err)
+	}
+
+	if value, _ := decoded.Get("dog and cat"); !reflect.DeepEqual(value, []string{"pets", "animals"}) {
+		t.Errorf("expected Get for %q to be %v, got %v", "dog and cat", []string{"pets", "animals"}, value)
+	}
+}
+
+func TestSimpleTrieValueCodec(t *testing.T) {
+	trie := NewSimpleTrie[string]()
+	trie.SetValueCodec(lineCodec{})
+	trie.Put("dog", "d")
+	trie.Put("dog and cat", "c")
+
+	data, err := trie.MarshalBinary()
+	if err != nil {
+		t.Fatalf("expected MarshalBinary to succeed, got %v", err)
+	}
+	if !bytes.Contains(data, []byte("d\nc\n")) {
+		t.Errorf("expected the values to be encoded by the codec in preorder")
+	}
+
+	decoded := NewSimpleTrie[string]()
+	decoded.SetValueCodec(lineCodec{})
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("expected UnmarshalBinary to succeed, got %v", err)
+	}
+	if value, _ := decoded.Get("dog and cat"); value != "c" {
+		t.Errorf("expected Get for %q to be %q, got %q", "dog and cat", "c", value)
+	}
+}
+
+func TestSimpleTrieMarshalJSON(t *testing.T) {
+	trie := NewSimpleTrie[int]()
+	trie.Put("cat", 0)
+	trie.Put("dog", 2)
+	trie.Put("dog and", 3)
+	trie.Put("dog and cat", 4)
+	trie.Put("dog or", 5)
+	trie.Delete("dog")
+
+	data, err := trie.MarshalJSON()
+	if err != nil {
+		t.Fatalf("expected MarshalJSON to succeed, got %v", err)
+	}
+
+	expected := `{"cat":{"value":0},"dog":{"children":{"and":{"value":3,"children":{"cat":{"value":4}}},"or":{"value":5}}}}`
+	if string(data) != expected {
+		t.Errorf("expected MarshalJSON to be %s, got %s", expected, data)
+	}
+
+	if data, _ := NewSimpleTrie[int]().MarshalJSON(); string(data) != "{}" {
+		t.Errorf("expected MarshalJSON of an empty trie to be {}, got %s", data)
+	}
+}
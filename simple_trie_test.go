@@ -2,10 +2,11 @@ package go_tries
 
 import (
 	"crypto/rand"
+	"reflect"
 	"testing"
 )
 
-var words [1000]string // random string words
+var words [1000]string // random string words, without zero bytes
 const bytesPerKey = 30
 
 var phrases [1000]string // random phrases
@@ -19,7 +20,7 @@ func init() {
 		if _, err := rand.Read(key); err != nil {
 			panic("error generating random byte slice")
 		}
-		phrases[i] = string(key)
+		words[i] = string(nonZero(key))
 	}
 
 	// path keys
@@ -31,14 +32,24 @@ func init() {
 			if _, err := rand.Read(part); err != nil {
 				panic("error generating random byte slice")
 			}
-			key += string(part)
+			key += string(nonZero(part))
 		}
 		phrases[i] = string(key)
 	}
 }
 
+// Replaces zero bytes, which the DoubleArrayTrie does not accept in keys
+func nonZero(key []byte) []byte {
+	for i := range key {
+		if key[i] == 0 {
+			key[i] = 1
+		}
+	}
+	return key
+}
+
 func TestNilCases(t *testing.T)  {
-	b := NewSimpleTrie()
+	b := NewSimpleTrie[int]()
 
 	cases := []struct {
 		key   string
@@ -56,33 +67,211 @@ func TestNilCases(t *testing.T)  {
 
 	// subsequent put
 	for _, c := range cases {
-		b.Add(c.key, c.value)
+		b.Put(c.key, c.value)
 	}
 
 	expectNilValues := []string{"", "c", "ca", "caterpillar2", "other"}
 
 	// get nil
 	for _, key := range expectNilValues {
-		if value := b.Get(key); value != nil {
+		if value, ok := b.Get(key); ok {
 			t.Errorf("expected key %s to have value nil, got %v", key, value)
 		}
 	}
 }
 
+func TestZeroValue(t *testing.T) {
+	b := NewSimpleTrie[int]()
+
+	b.Put("cat", 0)
+
+	if value, ok := b.Get("cat"); value != 0 || ok != true {
+		t.Errorf("expected key %s to have value %v, got %v", "cat", 0, value)
+	}
+
+	if old, replaced := b.Put("cat", 1); old != 0 || replaced != true {
+		t.Errorf("expected Put for %s to replace %v, got %v", "cat", 0, old)
+	}
+
+	if old, ok := b.Delete("cat"); old != 1 || ok != true {
+		t.Errorf("expected Delete for %s to remove %v, got %v", "cat", 1, old)
+	}
+
+	if _, ok := b.Delete("cat"); ok != false {
+		t.Errorf("expected second Delete for %s to be %v, got %v", "cat", false, ok)
+	}
+
+	if b.Len() != 0 {
+		t.Errorf("expected Len to be %v, got %v", 0, b.Len())
+	}
+}
+
+
+func TestSimpleTrieWalkPrefix(t *testing.T) {
+	b := NewSimpleTrie[int]()
+
+	b.Put("cat", 0)
+	b.Put("fox", 1)
+	b.Put("dog", 2)
+	b.Put("dog and", 3)
+	b.Put("dog and cat", 4)
+	b.Put("dogs", 5)
+	b.Put("dog bone", 6)
+
+	cases := []struct {
+		prefix string
+		keys   []string
+	}{
+		{"dog", []string{"dog", "dog and", "dog and cat", "dog bone"}},
+		{"dog and", []string{"dog and", "dog and cat"}},
+		{"do", nil},
+		{"", []string{"cat", "dog", "dog and", "dog and cat", "dog bone", "dogs", "fox"}},
+	}
+
+	for _, c := range cases {
+		var keys []string
+		b.WalkPrefix(c.prefix, func(key string, value int) bool {
+			keys = append(keys, key)
+			return true
+		})
+		if !reflect.DeepEqual(keys, c.keys) {
+			t.Errorf("expected WalkPrefix for %q to yield %v, got %v", c.prefix, c.keys, keys)
+		}
+	}
+
+	var keys []string
+	b.WalkPrefix("dog", func(key string, value int) bool {
+		keys = append(keys, key)
+		return len(keys) < 2
+	})
+	if !reflect.DeepEqual(keys, []string{"dog", "dog and"}) {
+		t.Errorf("expected stopped WalkPrefix to yield %v, got %v", []string{"dog", "dog and"}, keys)
+	}
+}
+
+func TestSimpleTrieLongestPrefix(t *testing.T) {
+	b := NewSimpleTrie[int]()
+
+	b.Put("dog", 0)
+	b.Put("dog and cat", 1)
+	b.Put("cat", 2)
+
+	cases := []struct {
+		key     string
+		matched string
+		value   int
+		ok      bool
+	}{
+		{"dog and cat food", "dog and cat", 1, true},
+		{"dog and mouse", "dog", 0, true},
+		{"dogs", "", 0, false},
+		{"cat", "cat", 2, true},
+		{"mouse", "", 0, false},
+	}
+
+	for _, c := range cases {
+		matched, value, ok := b.LongestPrefix(c.key)
+		if matched != c.matched || value != c.value || ok != c.ok {
+			t.Errorf("expected LongestPrefix for %q to be (%q, %v, %v), got (%q, %v, %v)", c.key, c.matched, c.value, c.ok, matched, value, ok)
+		}
+	}
+}
+
+func TestSimpleTrieIterator(t *testing.T) {
+	b := NewSimpleTrie[int]()
+
+	keys := []string{"cat", "dog", "dog and", "dog and cat", "dog bone", "dogs", "fox"}
+	for i, key := range keys {
+		b.Put(key, i)
+	}
+	b.Put("dog and cat food", 7)
+	b.Delete("dog and cat food")
+
+	it := b.Iterator()
+	for i, key := range keys {
+		if it.Next() != true || it.Key() != key || it.Value() != i {
+			t.Fatalf("expected Next to move to %q, got %q", key, it.Key())
+		}
+	}
+	if it.Next() != false {
+		t.Errorf("expected Next past the last key to be %v, got %v", false, true)
+	}
+	for i := len(keys) - 1; i >= 0; i-- {
+		if it.Prev() != true || it.Key() != keys[i] || it.Value() != i {
+			t.Fatalf("expected Prev to move to %q, got %q", keys[i], it.Key())
+		}
+	}
+	if it.Prev() != false {
+		t.Errorf("expected Prev before the first key to be %v, got %v", false, true)
+	}
+}
+
+func TestSimpleTrieIteratorNormalisedKeys(t *testing.T) {
+	b := NewSimpleTrie[int]()
+
+	b.Put("dog", 0)
+	b.Put("dog ", 1)
+	b.Put("a b", 2)
+	b.Put("a\tb", 3)
+
+	// Word order puts the words of "a b" before the single word "a\tb"
+	keys := []string{"a b", "a\tb", "dog"}
+	values := []int{2, 3, 1}
+
+	it := b.Iterator()
+	for i, key := range keys {
+		if it.Next() != true || it.Key() != key || it.Value() != values[i] {
+			t.Fatalf("expected Next to move to %q, got %q", key, it.Key())
+		}
+	}
+}
+
+func TestSimpleTrieSeek(t *testing.T) {
+	b := NewSimpleTrie[int]()
+
+	for i, key := range []string{"cat", "dog", "dog and", "dog and cat", "dog bone", "dogs", "fox"} {
+		b.Put(key, i)
+	}
+
+	cases := []struct {
+		key  string
+		next string
+		prev string
+	}{
+		{"", "cat", ""},
+		{"dog", "dog", "cat"},
+		{"dog a", "dog and", "dog"},
+		{"dog and cat", "dog and cat", "dog and"},
+		{"dog and mouse", "dog bone", "dog and cat"},
+		{"dog cat", "dogs", "dog bone"},
+		{"zebra", "", "fox"},
+	}
+
+	for _, c := range cases {
+		it := b.Seek(c.key)
+		if it.Next(); it.Key() != c.next {
+			t.Errorf("expected Next after Seek for %q to be %q, got %q", c.key, c.next, it.Key())
+		}
+		it = b.Seek(c.key)
+		if it.Prev(); it.Key() != c.prev {
+			t.Errorf("expected Prev after Seek for %q to be %q, got %q", c.key, c.prev, it.Key())
+		}
+	}
+}
 
 func BenchmarkSimpleTriePutStringKey(b *testing.B) {
-	trie := NewSimpleTrie()
+	trie := NewSimpleTrie[int]()
 	b.ResetTimer()
 	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
-		trie.Add(words[i%len(words)], i)
+		trie.Put(words[i%len(words)], i)
 	}
 }
 
 func BenchmarkSimpleTrieGetStringKey(b *testing.B) {
-	trie := NewSimpleTrie()
+	trie := NewSimpleTrie[int]()
 	for i := 0; i < b.N; i++ {
-		trie.Add(words[i%len(words)], i)
+		trie.Put(words[i%len(words)], i)
 	}
 	b.ResetTimer()
 	b.ReportAllocs()
@@ -94,18 +283,18 @@ func BenchmarkSimpleTrieGetStringKey(b *testing.B) {
 // Phrase keys
 
 func BenchmarkSimpleTriePutPhraseKey(b *testing.B) {
-	trie := NewSimpleTrie()
+	trie := NewSimpleTrie[int]()
 	b.ResetTimer()
 	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
-		trie.Add(phrases[i%len(phrases)], i)
+		trie.Put(phrases[i%len(phrases)], i)
 	}
 }
 
 func BenchmarkSimpleTrieGetPhraseKey(b *testing.B) {
-	trie := NewSimpleTrie()
+	trie := NewSimpleTrie[int]()
 	for i := 0; i < b.N; i++ {
-		trie.Add(phrases[i%len(phrases)], i)
+		trie.Put(phrases[i%len(phrases)], i)
 	}
 	b.ResetTimer()
 	b.ReportAllocs()
@@ -113,3 +302,94 @@ func BenchmarkSimpleTrieGetPhraseKey(b *testing.B) {
 		trie.Get(phrases[i%len(phrases)])
 	}
 }
+
+func BenchmarkSimpleTrieLongestPrefixPhraseKey(b *testing.B) {
+	trie := NewSimpleTrie[int]()
+	var inputs [len(phrases)]string
+	for i := 0; i < len(phrases); i++ {
+		trie.Put(phrases[i], i)
+		inputs[i] = phrases[i] + " suffix"
+	}
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trie.LongestPrefix(inputs[i%len(inputs)])
+	}
+}
+
+func TestSimpleTrieWithSeparator(t *testing.T) {
+	trie := NewSimpleTrie[int](WithSeparator("/"))
+	trie.Put("usr/local/bin", 1)
+	trie.Put("/usr/bin", 2)
+	trie.Put("usr/local", 3)
+	trie.Put("a b", 4)
+
+	if value, ok := trie.Get("usr/local/bin"); value != 1 || ok != true {
+		t.Errorf("expected Get for %q to be (%v, %v), got (%v, %v)", "usr/local/bin", 1, true, value, ok)
+	}
+	if value, ok := trie.Get("usr/bin/"); value != 2 || ok != true {
+		t.Errorf("expected Get for %q to be (%v, %v), got (%v, %v)", "usr/bin/", 2, true, value, ok)
+	}
+	if _, ok := trie.Get("a"); ok {
+		t.Errorf("expected Get for %q to be %v, got %v", "a", false, ok)
+	}
+
+	var keys []string
+	trie.WalkPrefix("/usr", func(key string, value int) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if expected := []string{"usr/bin", "usr/local", "usr/local/bin"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected WalkPrefix to visit %q, got %q", expected, keys)
+	}
+
+	it := trie.Seek("usr/local")
+	if !it.Next() || it.Key() != "usr/local" {
+		t.Errorf("expected Seek to move to %q, got %q", "usr/local", it.Key())
+	}
+
+	if key, value, ok := trie.LongestPrefix("usr/local/lib"); key != "usr/local" || value != 3 || ok != true {
+		t.Errorf("expected LongestPrefix to be (%q, %v, %v), got (%q, %v, %v)", "usr/local", 3, true, key, value, ok)
+	}
+
+	trie.Delete("usr/local/bin")
+	if _, ok := trie.Get("usr/local/bin"); ok {
+		t.Errorf("expected Get for %q to be %v, got %v", "usr/local/bin", false, ok)
+	}
+}
+
+func TestSimpleTrieWithRuneTokenizer(t *testing.T) {
+	trie := NewSimpleTrie[int](WithTokenizer(RuneTokenizer{}))
+	trie.Put("héllo", 1)
+	trie.Put("hé", 2)
+
+	if value, ok := trie.Get("héllo"); value != 1 || ok != true {
+		t.Errorf("expected Get for %q to be (%v, %v), got (%v, %v)", "héllo", 1, true, value, ok)
+	}
+
+	// Runes are joined without a separator
+	var keys []string
+	trie.WalkPrefix("h", func(key string, value int) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if expected := []string{"hé", "héllo"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected WalkPrefix to visit %q, got %q", expected, keys)
+	}
+}
+
+func TestSimpleTrieWithMultiByteSeparator(t *testing.T) {
+	trie := NewSimpleTrie[int](WithSeparator("::"))
+	trie.Put("std::io::Read", 1)
+	trie.Put("std::io", 2)
+
+	if value, ok := trie.Get("std::io::Read"); value != 1 || ok != true {
+		t.Errorf("expected Get for %q to be (%v, %v), got (%v, %v)", "std::io::Read", 1, true, value, ok)
+	}
+	if _, ok := trie.Get("std"); ok {
+		t.Errorf("expected Get for %q to be %v, got %v", "std", false, ok)
+	}
+	if key, _, _ := trie.LongestPrefix("std::io::Write"); key != "std::io" {
+		t.Errorf("expected LongestPrefix to be %q, got %q", "std::io", key)
+	}
+}
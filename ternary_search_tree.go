@@ -0,0 +1,295 @@
+package go_tries
+
+import "fmt"
+
+// TernarySearchTree stores one byte per node with three links: lo and hi
+// to nodes holding smaller and bigger bytes at the same position and eq to
+// the next byte of the key. Nodes only hold the bytes that are used, which
+// makes it smaller than a map of children per node.
+type TernarySearchTree[V any] struct {
+	// Root node
+	root *tstNode[V]
+	// Value of the empty key, which has no node
+	empty    V
+	hasEmpty bool
+	// Number of keys stored
+	size int
+}
+
+type tstNode[V any] struct {
+	// Byte of the node
+	ch byte
+	// Nodes with smaller bytes, the next byte and bigger bytes
+	lo, eq, hi *tstNode[V]
+	// Value of Node
+	value V
+	// Whether the node holds a value
+	hasValue bool
+}
+
+// NewTernarySearchTree allocates and returns a new *TernarySearchTree.
+func NewTernarySearchTree[V any]() *TernarySearchTree[V] {
+	return &TernarySearchTree[V]{}
+}
+
+// Len returns the number of keys stored in the tree.
+func (tree *TernarySearchTree[V]) Len() int {
+	return tree.size
+}
+
+// Returns the node of the last byte of key or nil
+func (tree *TernarySearchTree[V]) find(key string) *tstNode[V] {
+	n := tree.root
+	i := 0
+	for n != nil {
+		switch {
+		case key[i] < n.ch:
+			n = n.lo
+		case key[i] > n.ch:
+			n = n.hi
+		default:
+			i++
+			if i == len(key) {
+				return n
+			}
+			n = n.eq
+		}
+	}
+	return nil
+}
+
+// Get returns the value stored at the given key and whether the key was
+// found.
+func (tree *TernarySearchTree[V]) Get(key string) (V, bool) {
+	if key == "" {
+		return tree.empty, tree.hasEmpty
+	}
+	if n := tree.find(key); n != nil {
+		return n.value, n.hasValue
+	}
+	var zero V
+	return zero, false
+}
+
+// Lookup returns the value stored at the given key or ErrKeyNotFound if
+// the key is not stored.
+func (tree *TernarySearchTree[V]) Lookup(key string) (V, error) {
+	value, ok := tree.Get(key)
+	if !ok {
+		return value, notFound(key)
+	}
+	return value, nil
+}
+
+// Insert stores value at the given key. Every key can be stored, so it
+// always returns nil.
+func (tree *TernarySearchTree[V]) Insert(key string, value V) error {
+	tree.Put(key, value)
+	return nil
+}
+
+// Put stores value at the given key. It returns the previous value and
+// whether it was replaced.
+func (tree *TernarySearchTree[V]) Put(key string, value V) (V, bool) {
+	var old V
+	var replaced bool
+	if key == "" {
+		old, replaced = tree.empty, tree.hasEmpty
+		tree.empty, tree.hasEmpty = value, true
+	} else {
+		ref := &tree.root
+		i := 0
+		for {
+			n := *ref
+			if n == nil {
+				n = &tstNode[V]{ch: key[i]}
+				*ref = n
+			}
+
+			if key[i] < n.ch {
+				ref = &n.lo
+			} else if key[i] > n.ch {
+				ref = &n.hi
+			} else if i+1 < len(key) {
+				ref = &n.eq
+				i++
+			} else {
+				old, replaced = n.value, n.hasValue
+				n.value, n.hasValue = value, true
+				break
+			}
+		}
+	}
+
+	if !replaced {
+		tree.size += 1
+	}
+	return old, replaced
+}
+
+// Delete removes the given key together with the nodes no other key goes
+// through. It returns the removed value and whether the key was found.
+func (tree *TernarySearchTree[V]) Delete(key string) (V, bool) {
+	var zero V
+	var old V
+	var ok bool
+	if key == "" {
+		old, ok = tree.empty, tree.hasEmpty
+		tree.empty, tree.hasEmpty = zero, false
+	} else {
+		old, ok = tree.remove(&tree.root, key, 0)
+	}
+
+	if ok {
+		tree.size -= 1
+	}
+	return old, ok
+}
+
+func (tree *TernarySearchTree[V]) remove(ref **tstNode[V], key string, i int) (V, bool) {
+	var zero V
+	n := *ref
+	if n == nil {
+		return zero, false
+	}
+
+	var old V
+	var ok bool
+	switch {
+	case key[i] < n.ch:
+		old, ok = tree.remove(&n.lo, key, i)
+	case key[i] > n.ch:
+		old, ok = tree.remove(&n.hi, key, i)
+	case i+1 < len(key):
+		old, ok = tree.remove(&n.eq, key, i+1)
+	default:
+		old, ok = n.value, n.hasValue
+		n.value, n.hasValue = zero, false
+	}
+
+	// Unlink a node no key ends at or continues through when at most one
+	// of its siblings has to take its place
+	if ok && !n.hasValue && n.eq == nil {
+		if n.lo == nil {
+			*ref = n.hi
+		} else if n.hi == nil {
+			*ref = n.lo
+		}
+	}
+	return old, ok
+}
+
+// PutBalanced stores keys with their values so that the tree is balanced.
+// Keys must be sorted in ascending order without duplicates and match values
+// in length, otherwise an error wrapping ErrInvalidKey is returned and
+// nothing is stored. Inserting sorted keys one by one would degrade the lo
+// and hi links into lists, so the middle key of every range is inserted
+// before its halves.
+func (tree *TernarySearchTree[V]) PutBalanced(keys []string, values []V) error {
+	if len(keys) != len(values) {
+		return fmt.Errorf("%w: %d keys for %d values", ErrInvalidKey, len(keys), len(values))
+	}
+	for i := 1; i < len(keys); i++ {
+		if keys[i-1] >= keys[i] {
+			return fmt.Errorf("%w: %q is not sorted after %q", ErrInvalidKey, keys[i], keys[i-1])
+		}
+	}
+	tree.putBalanced(keys, values)
+	return nil
+}
+
+// Inserts the middle key of keys before the keys on either side of it
+func (tree *TernarySearchTree[V]) putBalanced(keys []string, values []V) {
+	if len(keys) == 0 {
+		return
+	}
+
+	mid := len(keys) / 2
+	tree.Put(keys[mid], values[mid])
+	tree.putBalanced(keys[:mid], values[:mid])
+	tree.putBalanced(keys[mid+1:], values[mid+1:])
+}
+
+// WalkPrefix calls fn for every key that starts with prefix, until fn
+// returns false. Keys are visited in ascending byte order.
+func (tree *TernarySearchTree[V]) WalkPrefix(prefix string, fn func(key string, value V) bool) {
+	if prefix == "" {
+		if tree.hasEmpty && !fn("", tree.empty) {
+			return
+		}
+		tree.root.walk(nil, fn)
+		return
+	}
+
+	n := tree.find(prefix)
+	if n == nil {
+		return
+	}
+	if n.hasValue && !fn(prefix, n.value) {
+		return
+	}
+	n.eq.walk([]byte(prefix), fn)
+}
+
+// Visits the keys below n, whose path spells buf, in order. Returns false
+// if fn stopped the walk.
+func (n *tstNode[V]) walk(buf []byte, fn func(key string, value V) bool) bool {
+	if n == nil {
+		return true
+	}
+	if !n.lo.walk(buf, fn) {
+		return false
+	}
+	key := append(buf, n.ch)
+	if n.hasValue && !fn(string(key), n.value) {
+		return false
+	}
+	if !n.eq.walk(key, fn) {
+		return false
+	}
+	return n.hi.walk(buf, fn)
+}
+
+// WalkPattern calls fn for every key of the same length as pattern that
+// matches it, until fn returns false. The wildcard byte in pattern matches
+// any single byte. Keys are visited in ascending byte order.
+func (tree *TernarySearchTree[V]) WalkPattern(pattern string, wildcard byte, fn func(key string, value V) bool) {
+	if pattern == "" {
+		if tree.hasEmpty {
+			fn("", tree.empty)
+		}
+		return
+	}
+	tree.root.match(pattern, wildcard, 0, nil, fn)
+}
+
+// Visits the keys below n that match pattern from i on. Returns false if
+// fn stopped the walk.
+func (n *tstNode[V]) match(pattern string, wildcard byte, i int, buf []byte, fn func(key string, value V) bool) bool {
+	if n == nil {
+		return true
+	}
+
+	ch := pattern[i]
+	if ch == wildcard || ch < n.ch {
+		if !n.lo.match(pattern, wildcard, i, buf, fn) {
+			return false
+		}
+	}
+
+	if ch == wildcard || ch == n.ch {
+		key := append(buf, n.ch)
+		if i+1 == len(pattern) {
+			if n.hasValue && !fn(string(key), n.value) {
+				return false
+			}
+		} else if !n.eq.match(pattern, wildcard, i+1, key, fn) {
+			return false
+		}
+	}
+
+	if ch == wildcard || ch > n.ch {
+		return n.hi.match(pattern, wildcard, i, buf, fn)
+	}
+	return true
+}
@@ -0,0 +1,267 @@
+package go_tries
+
+import (
+	"errors"
+	mrand "math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestTernarySearchTreeGetPut(t *testing.T) {
+	tst := NewTernarySearchTree[int]()
+
+	keys := []string{"cute", "cup", "at", "as", "he", "us", "i", ""}
+	for i, key := range keys {
+		tst.Put(key, i)
+	}
+
+	for i, key := range keys {
+		if value, ok := tst.Get(key); value != i || ok != true {
+			t.Errorf("expected Get for %q to be %v, got %v", key, i, value)
+		}
+	}
+
+	for _, key := range []string{"cu", "cuter", "a", "x"} {
+		if _, ok := tst.Get(key); ok != false {
+			t.Errorf("expected Get for %q to be %v, got %v", key, false, true)
+		}
+	}
+
+	if tst.Len() != len(keys) {
+		t.Errorf("expected Len to be %v, got %v", len(keys), tst.Len())
+	}
+}
+
+func TestTernarySearchTreeDelete(t *testing.T) {
+	tst := NewTernarySearchTree[int]()
+
+	tst.Put("cute", 0)
+	tst.Put("cup", 1)
+
+	if _, ok := tst.Delete("cu"); ok != false {
+		t.Errorf("expected Delete for %q to be %v, got %v", "cu", false, true)
+	}
+
+	if value, ok := tst.Delete("cute"); value != 0 || ok != true {
+		t.Errorf("expected Delete for %q to be %v, got %v", "cute", 0, value)
+	}
+
+	// Only the nodes of "cup" are left
+	if n := tst.root.eq.eq; n.ch != 'p' || n.lo != nil || n.hi != nil || n.eq != nil {
+		t.Errorf("expected nodes of %q to be pruned", "cute")
+	}
+
+	tst.Delete("cup")
+	if tst.root != nil || tst.Len() != 0 {
+		t.Errorf("expected empty tree, got %v keys", tst.Len())
+	}
+}
+
+func TestTernarySearchTreeWalkPrefix(t *testing.T) {
+	tst := NewTernarySearchTree[int]()
+
+	for i, key := range []string{"cute", "cup", "cu", "at", "as", "he"} {
+		tst.Put(key, i)
+	}
+
+	cases := []struct {
+		prefix string
+		keys   []string
+	}{
+		{"cu", []string{"cu", "cup", "cute"}},
+		{"a", []string{"as", "at"}},
+		{"x", nil},
+		{"", []string{"as", "at", "cu", "cup", "cute", "he"}},
+	}
+
+	for _, c := range cases {
+		var keys []string
+		tst.WalkPrefix(c.prefix, func(key string, value int) bool {
+			keys = append(keys, key)
+			return true
+		})
+		if !reflect.DeepEqual(keys, c.keys) {
+			t.Errorf("expected WalkPrefix for %q to yield %v, got %v", c.prefix, c.keys, keys)
+		}
+	}
+}
+
+func TestTernarySearchTreeWalkPattern(t *testing.T) {
+	tst := NewTernarySearchTree[int]()
+
+	for i, key := range []string{"cat", "cot", "cut", "cats", "bat", "ca", "c?t"} {
+		tst.Put(key, i)
+	}
+
+	cases := []struct {
+		pattern string
+		keys    []string
+	}{
+		{"c?t", []string{"c?t", "cat", "cot", "cut"}},
+		{"?at", []string{"bat", "cat"}},
+		{"???", []string{"bat", "c?t", "cat", "cot", "cut"}},
+		{"ca?s", []string{"cats"}},
+		{"c", nil},
+	}
+
+	for _, c := range cases {
+		var keys []string
+		tst.WalkPattern(c.pattern, '?', func(key string, value int) bool {
+			keys = append(keys, key)
+			return true
+		})
+		if !reflect.DeepEqual(keys, c.keys) {
+			t.Errorf("expected WalkPattern for %q to yield %v, got %v", c.pattern, c.keys, keys)
+		}
+	}
+}
+
+// Returns the height of the lo and hi links below n
+func (n *tstNode[V]) height() int {
+	if n == nil {
+		return 0
+	}
+	lo, hi := n.lo.height(), n.hi.height()
+	if lo > hi {
+		return lo + 1
+	}
+	return hi + 1
+}
+
+func TestTernarySearchTreePutBalanced(t *testing.T) {
+	var keys []string
+	var values []int
+	for i := 0; i < 26; i++ {
+		keys = append(keys, string(rune('a'+i)))
+		values = append(values, i)
+	}
+
+	tst := NewTernarySearchTree[int]()
+	if err := tst.PutBalanced(keys, values); err != nil {
+		t.Fatalf("expected PutBalanced to succeed, got %v", err)
+	}
+
+	if h := tst.root.height(); h > 5 {
+		t.Errorf("expected height of balanced tree to be at most %v, got %v", 5, h)
+	}
+
+	for i, key := range keys {
+		if value, ok := tst.Get(key); value != i || ok != true {
+			t.Errorf("expected Get for %q to be %v, got %v", key, i, value)
+		}
+	}
+}
+
+func TestTernarySearchTreePutBalancedInvalidInput(t *testing.T) {
+	cases := []struct {
+		name   string
+		keys   []string
+		values []int
+	}{
+		{"unsorted", []string{"b", "a"}, []int{1, 2}},
+		{"duplicate", []string{"a", "a"}, []int{1, 2}},
+		{"length mismatch", []string{"a", "b"}, []int{1}},
+	}
+
+	for _, c := range cases {
+		tst := NewTernarySearchTree[int]()
+		if err := tst.PutBalanced(c.keys, c.values); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%s: expected PutBalanced to fail with %v, got %v", c.name, ErrInvalidKey, err)
+		}
+		if tst.Len() != 0 {
+			t.Errorf("%s: expected Len to be %v, got %v", c.name, 0, tst.Len())
+		}
+	}
+}
+
+func TestTernarySearchTreeRandomKeys(t *testing.T) {
+	r := mrand.New(mrand.NewSource(6))
+	tst := NewTernarySearchTree[string]()
+	ref := make(map[string]string)
+
+	for i := 0; i < 10000; i++ {
+		key := string(randomKeyFrom(r, "abcd", 5))
+		if r.Intn(3) == 0 {
+			_, ok := tst.Delete(key)
+			if _, exists := ref[key]; ok != exists {
+				t.Fatalf("expected Delete for %q to be %v, got %v", key, exists, ok)
+			}
+			delete(ref, key)
+		} else {
+			tst.Put(key, key)
+			ref[key] = key
+		}
+	}
+
+	var expected []string
+	for key := range ref {
+		expected = append(expected, key)
+		if value, ok := tst.Get(key); value != key || ok != true {
+			t.Errorf("expected Get for %q to be %q, got %q", key, key, value)
+		}
+	}
+	sort.Strings(expected)
+
+	var keys []string
+	tst.WalkPrefix("", func(key string, value string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected WalkPrefix to yield %v, got %v", expected, keys)
+	}
+}
+
+func BenchmarkTernarySearchTreePutStringKey(b *testing.B) {
+	trie := NewTernarySearchTree[int]()
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trie.Put(words[i%len(words)], i)
+	}
+}
+
+func BenchmarkTernarySearchTreeGetStringKey(b *testing.B) {
+	trie := NewTernarySearchTree[int]()
+	for i := 0; i < b.N; i++ {
+		trie.Put(words[i%len(words)], i)
+	}
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trie.Get(words[i%len(words)])
+	}
+}
+
+func BenchmarkTernarySearchTreePutPhraseKey(b *testing.B) {
+	trie := NewTernarySearchTree[int]()
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trie.Put(phrases[i%len(phrases)], i)
+	}
+}
+
+func BenchmarkTernarySearchTreeGetPhraseKey(b *testing.B) {
+	trie := NewTernarySearchTree[int]()
+	for i := 0; i < b.N; i++ {
+		trie.Put(phrases[i%len(phrases)], i)
+	}
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trie.Get(phrases[i%len(phrases)])
+	}
+}
+
+func BenchmarkTernarySearchTreePutBalanced(b *testing.B) {
+	keys := append([]string(nil), words[:]...)
+	sort.Strings(keys)
+	values := make([]int, len(keys))
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewTernarySearchTree[int]().PutBalanced(keys, values)
+	}
+}
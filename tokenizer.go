@@ -0,0 +1,99 @@
+package go_tries
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenizer splits the keys of a SimpleTrie into the segments stored at
+// each level.
+type Tokenizer interface {
+	// Next splits the first segment off key and returns it together with
+	// the rest of key. An empty rest ends the key, so rest only holds what
+	// follows when another segment does.
+	Next(key string) (segment, rest string)
+	// Separator returns the string joining segments when keys are
+	// reported.
+	Separator() string
+}
+
+// SeparatorTokenizer returns a Tokenizer splitting keys on sep as SplitPath
+// does. Separators around segments are dropped.
+func SeparatorTokenizer(sep string) Tokenizer {
+	return separatorTokenizer{sep: sep}
+}
+
+type separatorTokenizer struct {
+	sep string
+}
+
+func (t separatorTokenizer) Next(key string) (string, string) {
+	return SplitPath(key, t.sep)
+}
+
+func (t separatorTokenizer) Separator() string {
+	return t.sep
+}
+
+// ByteTokenizer splits keys into single bytes.
+type ByteTokenizer struct{}
+
+func (ByteTokenizer) Next(key string) (string, string) {
+	if key == "" {
+		return "", ""
+	}
+	return key[:1], key[1:]
+}
+
+func (ByteTokenizer) Separator() string {
+	return ""
+}
+
+// RuneTokenizer splits keys into UTF-8 encoded runes. Invalid bytes are
+// segments of their own.
+type RuneTokenizer struct{}
+
+func (RuneTokenizer) Next(key string) (string, string) {
+	_, size := utf8.DecodeRuneInString(key)
+	return key[:size], key[size:]
+}
+
+func (RuneTokenizer) Separator() string {
+	return ""
+}
+
+// WordTokenizer splits keys into words, the runs of Unicode letters, marks
+// and digits, and drops everything between them. A key without words is a
+// single segment. Keys are reported with their words joined by a space.
+type WordTokenizer struct{}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r)
+}
+
+func notWordRune(r rune) bool {
+	return !isWordRune(r)
+}
+
+func (WordTokenizer) Next(key string) (string, string) {
+	start := strings.IndexFunc(key, isWordRune)
+	if start == -1 {
+		return key, ""
+	}
+	key = key[start:]
+
+	end := strings.IndexFunc(key, notWordRune)
+	if end == -1 {
+		return key, ""
+	}
+	segment, rest := key[:end], key[end:]
+	if strings.IndexFunc(rest, isWordRune) == -1 {
+		rest = ""
+	}
+	return segment, rest
+}
+
+func (WordTokenizer) Separator() string {
+	return " "
+}
@@ -0,0 +1,46 @@
+package go_tries
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Returns the segments t splits key into
+func segments(t Tokenizer, key string) []string {
+	var result []string
+	for segment, rest := t.Next(key); ; segment, rest = t.Next(rest) {
+		result = append(result, segment)
+		if rest == "" {
+			return result
+		}
+	}
+}
+
+func TestTokenizers(t *testing.T) {
+	cases := []struct {
+		name      string
+		tokenizer Tokenizer
+		key       string
+		segments  []string
+	}{
+		{"separator", SeparatorTokenizer("/"), "usr/local/bin", []string{"usr", "local", "bin"}},
+		{"separator leading", SeparatorTokenizer("/"), "/usr/bin/", []string{"usr", "bin"}},
+		{"separator dot", SeparatorTokenizer("."), "www.example.com", []string{"www", "example", "com"}},
+		{"separator empty key", SeparatorTokenizer("/"), "", []string{""}},
+		{"byte", ByteTokenizer{}, "héllo", []string{"h", "\xc3", "\xa9", "l", "l", "o"}},
+		{"byte empty key", ByteTokenizer{}, "", []string{""}},
+		{"rune", RuneTokenizer{}, "héllo", []string{"h", "é", "l", "l", "o"}},
+		{"rune invalid", RuneTokenizer{}, "a\xffb", []string{"a", "\xff", "b"}},
+		{"rune empty key", RuneTokenizer{}, "", []string{""}},
+		{"word", WordTokenizer{}, "Hello, wörld! 42 times.", []string{"Hello", "wörld", "42", "times"}},
+		{"word leading", WordTokenizer{}, "  --dog", []string{"dog"}},
+		{"word no words", WordTokenizer{}, "?!", []string{"?!"}},
+		{"word empty key", WordTokenizer{}, "", []string{""}},
+	}
+
+	for _, c := range cases {
+		if got := segments(c.tokenizer, c.key); !reflect.DeepEqual(got, c.segments) {
+			t.Errorf("%s: expected %q to split into %q, got %q", c.name, c.key, c.segments, got)
+		}
+	}
+}
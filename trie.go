@@ -1,8 +1,74 @@
 package go_tries
 
-// Abstract interface for Trie
-type Trie interface {
-	Get(key string) interface{}
-	Add(key string, value int) bool
-	Delete(key string) bool
+// Abstract interface for Trie.
+//
+// Implementations that cannot store every key, such as DoubleArrayTrie,
+// which rejects keys holding 0x00, expose a ValidKey method. Put ignores
+// such keys and returns the zero value and false, so callers check keys
+// with ValidKey first or use Insert from CheckedTrie, which reports why a
+// key was not stored.
+type Trie[V any] interface {
+	// Get returns the value stored at key and whether key was found.
+	Get(key string) (V, bool)
+	// Put stores value at key. It returns the previous value and whether
+	// it was replaced.
+	Put(key string, value V) (old V, replaced bool)
+	// Delete removes key. It returns the removed value and whether key
+	// was found.
+	Delete(key string) (V, bool)
+	// Len returns the number of keys stored.
+	Len() int
 }
+
+// Iterator is a cursor over the keys of a trie in order.
+type Iterator[V any] interface {
+	// Next moves to the next key and reports whether there is one.
+	Next() bool
+	// Prev moves to the previous key and reports whether there is one.
+	Prev() bool
+	// Key returns the key at the cursor.
+	Key() string
+	// Value returns the value at the cursor.
+	Value() V
+}
+
+// CheckedTrie reports why an operation failed instead of returning false.
+type CheckedTrie[V any] interface {
+	// Insert stores value at key, replacing any previous value. It returns
+	// ErrInvalidKey or ErrCapacity when the key cannot be stored.
+	Insert(key string, value V) error
+	// Lookup returns the value stored at key or an error wrapping
+	// ErrKeyNotFound.
+	Lookup(key string) (V, error)
+}
+
+// KeyValidator is implemented by tries that restrict their keys.
+type KeyValidator interface {
+	// ValidKey reports whether key can be stored.
+	ValidKey(key string) bool
+}
+
+var (
+	_ KeyValidator = (*DoubleArrayTrie[any])(nil)
+
+	_ Trie[any] = (*SimpleTrie[any])(nil)
+	_ Trie[any] = (*DoubleArrayTrie[any])(nil)
+	_ Trie[any] = (*RadixTree[any])(nil)
+	_ Trie[any] = (*AdaptiveRadixTree[any])(nil)
+	_ Trie[any] = (*TernarySearchTree[any])(nil)
+	_ Trie[any] = (*ConcurrentTrie[any])(nil)
+	_ Trie[any] = (*PersistentRadixTree[any])(nil)
+	_ Trie[any] = (*ShardedTrie[any])(nil)
+
+	_ CheckedTrie[any] = (*SimpleTrie[any])(nil)
+	_ CheckedTrie[any] = (*DoubleArrayTrie[any])(nil)
+	_ CheckedTrie[any] = (*RadixTree[any])(nil)
+	_ CheckedTrie[any] = (*AdaptiveRadixTree[any])(nil)
+	_ CheckedTrie[any] = (*TernarySearchTree[any])(nil)
+	_ CheckedTrie[any] = (*ConcurrentTrie[any])(nil)
+	_ CheckedTrie[any] = (*PersistentRadixTree[any])(nil)
+	_ CheckedTrie[any] = (*ShardedTrie[any])(nil)
+
+	_ Iterator[any] = (*SimpleTrieIterator[any])(nil)
+	_ Iterator[any] = (*DoubleArrayTrieIterator[any])(nil)
+)
@@ -0,0 +1,119 @@
+package go_tries
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckedTrie(t *testing.T) {
+	tries := map[string]CheckedTrie[int]{
+		"SimpleTrie":        NewSimpleTrie[int](),
+		"DoubleArrayTrie":   NewDoubleArrayTrie[int](),
+		"RadixTree":         NewRadixTree[int](),
+		"AdaptiveRadixTree": NewAdaptiveRadixTree[int](),
+		"TernarySearchTree": NewTernarySearchTree[int](),
+	}
+
+	for name, trie := range tries {
+		if _, err := trie.Lookup("dog"); !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("%s: expected Lookup for %q to fail with %v, got %v", name, "dog", ErrKeyNotFound, err)
+		}
+
+		if err := trie.Insert("dog", 1); err != nil {
+			t.Errorf("%s: expected Insert for %q to succeed, got %v", name, "dog", err)
+		}
+		if err := trie.Insert("dog", 2); err != nil {
+			t.Errorf("%s: expected Insert to replace %q, got %v", name, "dog", err)
+		}
+		if value, err := trie.Lookup("dog"); value != 2 || err != nil {
+			t.Errorf("%s: expected Lookup for %q to be (%v, %v), got (%v, %v)", name, "dog", 2, nil, value, err)
+		}
+	}
+}
+
+func TestDoubleArrayTrieInsertInvalidKey(t *testing.T) {
+	d := NewDoubleArrayTrie[int]()
+	if err := d.Insert("a\x00b", 1); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected Insert to fail with %v, got %v", ErrInvalidKey, err)
+	}
+	if _, err := d.Lookup("a\x00b"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected Lookup to fail with %v, got %v", ErrInvalidKey, err)
+	}
+	if d.Len() != 0 {
+		t.Errorf("expected Len to be %v, got %v", 0, d.Len())
+	}
+}
+
+func TestDoubleArrayTrieInsertCapacity(t *testing.T) {
+	defer func(limit int) { maxPosition = limit }(maxPosition)
+	maxPosition = 4096
+
+	d := NewDoubleArrayTrie[int]()
+	if err := d.Insert("short", 1); err != nil {
+		t.Fatalf("expected Insert to succeed, got %v", err)
+	}
+	long := strings.Repeat("x", 20)
+	if err := d.Insert(long, 2); !errors.Is(err, ErrCapacity) {
+		t.Errorf("expected Insert to fail with %v, got %v", ErrCapacity, err)
+	}
+	if _, ok := d.Get(long); ok {
+		t.Errorf("expected Get for %q to be %v, got %v", long, false, ok)
+	}
+}
+
+func TestDoubleArrayTrieInsertTailCapacity(t *testing.T) {
+	defer func(limit uint64) { maxTailLen = limit }(maxTailLen)
+	maxTailLen = 8
+
+	d := NewDoubleArrayTrie[int]()
+	if err := d.Insert("short", 1); err != nil {
+		t.Fatalf("expected Insert to succeed, got %v", err)
+	}
+	if err := d.Insert("longer", 2); !errors.Is(err, ErrCapacity) {
+		t.Errorf("expected Insert to fail with %v, got %v", ErrCapacity, err)
+	}
+	if err := d.WriteTail("longer", 2); !errors.Is(err, ErrCapacity) {
+		t.Errorf("expected WriteTail to fail with %v, got %v", ErrCapacity, err)
+	}
+	if _, ok := d.Get("longer"); ok || d.Len() != 1 {
+		t.Errorf("expected only %q to be stored, got Len %v", "short", d.Len())
+	}
+}
+
+func TestDoubleArrayTrieDeleteFullTail(t *testing.T) {
+	defer func(limit uint64) { maxTailLen = limit }(maxTailLen)
+
+	d := NewDoubleArrayTrie[int]()
+	d.Put("ab", 1)
+	d.Put("ac", 2)
+
+	// Folding the state of "a" into the leaf of "ac" needs a tail byte
+	maxTailLen = uint64(len(d.tail))
+	if _, ok := d.Delete("ab"); !ok {
+		t.Fatalf("expected Delete for %q to be %v, got %v", "ab", true, ok)
+	}
+	if value, ok := d.Get("ac"); value != 2 || ok != true {
+		t.Errorf("expected Get for %q to be %v, got %v", "ac", 2, value)
+	}
+	if err := d.Validate(); err != nil || d.Len() != 1 {
+		t.Errorf("expected a valid trie holding %v key, got %v with %v", 1, err, d.Len())
+	}
+}
+
+func TestWriteTailOutOfRange(t *testing.T) {
+	d := NewDoubleArrayTrie[int]()
+	d.WriteTail("hello", 1)
+
+	for _, pos := range []int{-1, 0, 3} {
+		if err := d.WriteTail("x", pos); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("expected WriteTail at %v to fail with %v, got %v", pos, ErrOutOfRange, err)
+		}
+	}
+	if string(d.tail) != "hello" || len(d.tails) != 1 {
+		t.Errorf("expected tail array value to be %q in %v segment, got %q in %v", "hello", 1, d.tail, len(d.tails))
+	}
+	if d.ReadTail(0) != "" || d.ReadTail(-3) != "" {
+		t.Errorf("expected ReadTail before the tail to be empty")
+	}
+}
@@ -0,0 +1,381 @@
+// Package triestest checks that an implementation of go_tries.Trie behaves
+// like the tries of this module.
+//
+// Keys are treated as byte strings: a prefix walk matches keys by their
+// leading bytes and visits them in ascending byte order. Tries that split
+// keys into words, such as SimpleTrie by default, must be configured to
+// split them into bytes. Keys used by the suite never hold 0x00, so tries
+// that reserve it as a terminator pass as well.
+//
+//	func TestConformance(t *testing.T) {
+//		triestest.RunConformance(t, func() go_tries.Trie[any] {
+//			return NewMyTrie[any]()
+//		})
+//	}
+package triestest
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	go_tries "github.com/theodesp/go-tries"
+)
+
+// Walker is implemented by tries that can visit the keys starting with a
+// prefix. The prefix walk tests are skipped for tries that do not
+// implement it.
+type Walker interface {
+	// WalkPrefix calls fn for every key that starts with prefix in
+	// ascending byte order, until fn returns false.
+	WalkPrefix(prefix string, fn func(key string, value any) bool)
+}
+
+// Keys sharing prefixes with each other, so that puts and deletes change
+// paths other keys still use
+var words = []string{
+	"romane",
+	"romanus",
+	"romulus",
+	"rubens",
+	"ruber",
+	"rubicon",
+	"rubicundus",
+	"rom",
+	"r",
+	"ro",
+	"rubicundusx",
+	"\xff",
+	"\xff\x01",
+	"\x01",
+	"é",
+	"ée",
+}
+
+// Returns the keys of ref that start with prefix in byte order
+func keysWithPrefix(ref map[string]any, prefix string) []string {
+	keys := []string{}
+	for key := range ref {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Returns the keys visited by a walk of prefix and fails on values that
+// differ from ref
+func walk(t *testing.T, trie go_tries.Trie[any], prefix string, ref map[string]any) []string {
+	t.Helper()
+	keys := []string{}
+	trie.(Walker).WalkPrefix(prefix, func(key string, value any) bool {
+		if expected := ref[key]; value != expected {
+			t.Errorf("expected WalkPrefix(%q) to visit %q with %v, got %v", prefix, key, expected, value)
+		}
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// Fails unless trie holds exactly the keys and values of ref
+func checkContents(t *testing.T, trie go_tries.Trie[any], ref map[string]any) {
+	t.Helper()
+	if trie.Len() != len(ref) {
+		t.Errorf("expected Len to be %v, got %v", len(ref), trie.Len())
+	}
+	for key, expected := range ref {
+		if value, ok := trie.Get(key); !ok || value != expected {
+			t.Errorf("expected Get(%q) to be (%v, true), got (%v, %v)", key, expected, value, ok)
+		}
+	}
+	if _, ok := trie.(Walker); ok {
+		if keys, expected := walk(t, trie, "", ref), keysWithPrefix(ref, ""); !reflect.DeepEqual(keys, expected) {
+			t.Errorf("expected WalkPrefix(\"\") to visit %q, got %q", expected, keys)
+		}
+	}
+}
+
+// Returns a new trie holding words, each stored with its index
+func populate(t *testing.T, newTrie func() go_tries.Trie[any]) (go_tries.Trie[any], map[string]any) {
+	t.Helper()
+	trie, ref := newTrie(), make(map[string]any)
+	for i, word := range words {
+		trie.Put(word, i)
+		ref[word] = i
+	}
+	return trie, ref
+}
+
+// RunConformance runs the tests every trie of this module passes against
+// tries returned by newTrie. Each call of newTrie must return a new empty
+// trie.
+//
+// Tries are only read from several goroutines at once, which every trie
+// must allow. RunConcurrentConformance covers tries that also allow
+// concurrent writes.
+func RunConformance(t *testing.T, newTrie func() go_tries.Trie[any]) {
+	t.Run("Empty", func(t *testing.T) {
+		trie := newTrie()
+		if trie.Len() != 0 {
+			t.Errorf("expected Len to be %v, got %v", 0, trie.Len())
+		}
+		for _, key := range []string{"", "a"} {
+			if value, ok := trie.Get(key); ok || value != nil {
+				t.Errorf("expected Get(%q) to be (%v, %v), got (%v, %v)", key, nil, false, value, ok)
+			}
+			if value, ok := trie.Delete(key); ok || value != nil {
+				t.Errorf("expected Delete(%q) to be (%v, %v), got (%v, %v)", key, nil, false, value, ok)
+			}
+		}
+	})
+
+	t.Run("Put", func(t *testing.T) {
+		trie, ref := newTrie(), make(map[string]any)
+		for i, word := range words {
+			if old, replaced := trie.Put(word, i); replaced || old != nil {
+				t.Errorf("expected Put(%q) to be (%v, %v), got (%v, %v)", word, nil, false, old, replaced)
+			}
+			ref[word] = i
+			if trie.Len() != len(ref) {
+				t.Errorf("expected Len to be %v, got %v", len(ref), trie.Len())
+			}
+		}
+		checkContents(t, trie, ref)
+
+		// Prefixes of stored keys are not stored themselves
+		for _, key := range []string{"roma", "rub", "rubicun", "\xff\x01\x01", "é"[:1]} {
+			if value, ok := trie.Get(key); ok {
+				t.Errorf("expected Get(%q) to be (%v, %v), got (%v, %v)", key, nil, false, value, ok)
+			}
+		}
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		trie, ref := populate(t, newTrie)
+		for i, word := range words {
+			if old, replaced := trie.Put(word, -i); !replaced || old != i {
+				t.Errorf("expected Put(%q) to be (%v, %v), got (%v, %v)", word, i, true, old, replaced)
+			}
+			ref[word] = -i
+		}
+		checkContents(t, trie, ref)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		trie, ref := populate(t, newTrie)
+		for i, word := range words {
+			if value, ok := trie.Delete(word); !ok || value != i {
+				t.Errorf("expected Delete(%q) to be (%v, %v), got (%v, %v)", word, i, true, value, ok)
+			}
+			if value, ok := trie.Delete(word); ok {
+				t.Errorf("expected second Delete(%q) to be (%v, %v), got (%v, %v)", word, nil, false, value, ok)
+			}
+			delete(ref, word)
+			checkContents(t, trie, ref)
+		}
+	})
+
+	t.Run("DeleteNestedKeys", func(t *testing.T) {
+		trie := newTrie()
+		trie.Put("abcd", 1)
+		trie.Put("ab", 2)
+		trie.Delete("abcd")
+		for _, key := range []string{"a", "abc", "abcd"} {
+			if value, ok := trie.Get(key); ok {
+				t.Errorf("expected Get(%q) to be (%v, %v), got (%v, %v)", key, nil, false, value, ok)
+			}
+		}
+		checkContents(t, trie, map[string]any{"ab": 2})
+
+		trie.Delete("ab")
+		checkContents(t, trie, map[string]any{})
+
+		// The emptied trie takes keys along the deleted paths again
+		trie.Put("abce", 3)
+		trie.Put("abd", 4)
+		checkContents(t, trie, map[string]any{"abce": 3, "abd": 4})
+	})
+
+	t.Run("EmptyKey", func(t *testing.T) {
+		trie, ref := populate(t, newTrie)
+		if old, replaced := trie.Put("", "empty"); replaced {
+			t.Errorf("expected Put(\"\") to be (%v, %v), got (%v, %v)", nil, false, old, replaced)
+		}
+		ref[""] = "empty"
+		checkContents(t, trie, ref)
+
+		if value, ok := trie.Delete(""); !ok || value != "empty" {
+			t.Errorf("expected Delete(\"\") to be (%v, %v), got (%v, %v)", "empty", true, value, ok)
+		}
+		delete(ref, "")
+		checkContents(t, trie, ref)
+	})
+
+	t.Run("NilValue", func(t *testing.T) {
+		trie := newTrie()
+		trie.Put("nil", nil)
+		if value, ok := trie.Get("nil"); !ok || value != nil {
+			t.Errorf("expected Get(%q) to be (%v, %v), got (%v, %v)", "nil", nil, true, value, ok)
+		}
+		if old, replaced := trie.Put("nil", nil); !replaced || old != nil {
+			t.Errorf("expected Put(%q) to be (%v, %v), got (%v, %v)", "nil", nil, true, old, replaced)
+		}
+		checkContents(t, trie, map[string]any{"nil": nil})
+		if value, ok := trie.Delete("nil"); !ok || value != nil {
+			t.Errorf("expected Delete(%q) to be (%v, %v), got (%v, %v)", "nil", nil, true, value, ok)
+		}
+		checkContents(t, trie, map[string]any{})
+	})
+
+	t.Run("WalkPrefix", func(t *testing.T) {
+		if _, ok := newTrie().(Walker); !ok {
+			t.Skip("trie does not implement WalkPrefix")
+		}
+		trie, ref := populate(t, newTrie)
+		trie.Put("", "empty")
+		ref[""] = "empty"
+
+		for _, prefix := range []string{"", "r", "ro", "rom", "roma", "romane", "romanex", "rub", "rubicundus", "x", "\xff", "é"[:1]} {
+			if keys, expected := walk(t, trie, prefix, ref), keysWithPrefix(ref, prefix); !reflect.DeepEqual(keys, expected) {
+				t.Errorf("expected WalkPrefix(%q) to visit %q, got %q", prefix, expected, keys)
+			}
+		}
+	})
+
+	t.Run("WalkPrefixStops", func(t *testing.T) {
+		if _, ok := newTrie().(Walker); !ok {
+			t.Skip("trie does not implement WalkPrefix")
+		}
+		trie, ref := populate(t, newTrie)
+		expected := keysWithPrefix(ref, "")
+		for n := 1; n <= len(expected); n++ {
+			var keys []string
+			trie.(Walker).WalkPrefix("", func(key string, value any) bool {
+				keys = append(keys, key)
+				return len(keys) < n
+			})
+			if !reflect.DeepEqual(keys, expected[:n]) {
+				t.Errorf("expected WalkPrefix stopped after %v keys to visit %q, got %q", n, expected[:n], keys)
+			}
+		}
+	})
+
+	t.Run("RandomOperations", func(t *testing.T) {
+		const alphabet = "ab\x01\xff"
+		r := rand.New(rand.NewSource(1))
+		randomKey := func() string {
+			key := make([]byte, r.Intn(6))
+			for i := range key {
+				key[i] = alphabet[r.Intn(len(alphabet))]
+			}
+			return string(key)
+		}
+
+		trie, ref := newTrie(), make(map[string]any)
+		for i := 0; i < 2000; i++ {
+			key := randomKey()
+			expected, expectedOk := ref[key]
+			switch r.Intn(3) {
+			case 0:
+				if old, replaced := trie.Put(key, i); old != expected || replaced != expectedOk {
+					t.Fatalf("expected Put(%q) to be (%v, %v), got (%v, %v)", key, expected, expectedOk, old, replaced)
+				}
+				ref[key] = i
+			case 1:
+				if value, ok := trie.Get(key); value != expected || ok != expectedOk {
+					t.Fatalf("expected Get(%q) to be (%v, %v), got (%v, %v)", key, expected, expectedOk, value, ok)
+				}
+			case 2:
+				if value, ok := trie.Delete(key); value != expected || ok != expectedOk {
+					t.Fatalf("expected Delete(%q) to be (%v, %v), got (%v, %v)", key, expected, expectedOk, value, ok)
+				}
+				delete(ref, key)
+			}
+			if trie.Len() != len(ref) {
+				t.Fatalf("expected Len to be %v, got %v", len(ref), trie.Len())
+			}
+		}
+		checkContents(t, trie, ref)
+	})
+
+	t.Run("ConcurrentReads", func(t *testing.T) {
+		trie, ref := populate(t, newTrie)
+		_, walker := trie.(Walker)
+
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for _, word := range words {
+					if value, ok := trie.Get(word); !ok || value != ref[word] {
+						t.Errorf("expected Get(%q) to be (%v, %v), got (%v, %v)", word, ref[word], true, value, ok)
+					}
+					trie.Get(word + "x")
+					trie.Len()
+					if walker {
+						trie.(Walker).WalkPrefix(word, func(key string, value any) bool { return true })
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+// RunConcurrentConformance runs the tests of RunConformance and checks that
+// tries returned by newTrie can be read and written by several goroutines
+// at once. Run it with -race.
+func RunConcurrentConformance(t *testing.T, newTrie func() go_tries.Trie[any]) {
+	RunConformance(t, newTrie)
+
+	t.Run("ConcurrentWrites", func(t *testing.T) {
+		const goroutines = 8
+		const keys = 200
+
+		trie := newTrie()
+		_, walker := trie.(Walker)
+
+		var wg sync.WaitGroup
+		for g := 0; g < goroutines; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < keys; i++ {
+					key := fmt.Sprintf("g%d/%d", g, i)
+					if old, replaced := trie.Put(key, i); replaced {
+						t.Errorf("expected Put(%q) to be (%v, %v), got (%v, %v)", key, nil, false, old, replaced)
+					}
+					if value, ok := trie.Get(key); !ok || value != i {
+						t.Errorf("expected Get(%q) to be (%v, %v), got (%v, %v)", key, i, true, value, ok)
+					}
+					trie.Get(fmt.Sprintf("g%d/%d", (g+1)%goroutines, i))
+					trie.Len()
+					if walker {
+						trie.(Walker).WalkPrefix(fmt.Sprintf("g%d/", (g+1)%goroutines), func(key string, value any) bool { return true })
+					}
+					if i%2 == 1 {
+						if value, ok := trie.Delete(key); !ok || value != i {
+							t.Errorf("expected Delete(%q) to be (%v, %v), got (%v, %v)", key, i, true, value, ok)
+						}
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+
+		ref := make(map[string]any)
+		for g := 0; g < goroutines; g++ {
+			for i := 0; i < keys; i += 2 {
+				ref[fmt.Sprintf("g%d/%d", g, i)] = i
+			}
+		}
+		checkContents(t, trie, ref)
+	})
+}
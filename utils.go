@@ -1,76 +1,207 @@
 package go_tries
 
-import "strings"
+import (
+	"strings"
+	"unicode/utf8"
+)
 
-// Get Next word from a key, a starting index and a path separator
-// Not used
+// NextWord returns the word of key that starts at start, including the
+// separator ending it, together with the index of the next word. The index
+// is -1 when the word is the last one, or when start is out of range, in
+// which case the word is empty. It is the index based counterpart of
+// SplitPath for callers that keep their position in key.
 func NextWord(key string, start int, sep rune) (segment string, nextIndex int) {
 	if len(key) == 0 || start < 0 || start > len(key)-1 {
 		return "", -1
 	}
-	end := strings.IndexRune(key[start:], sep) // next sep after 0th rune
+	end := strings.IndexRune(key[start:], sep)
 	if end == -1 {
 		return key[start:], -1
 	}
-	return key[start: start+end+1], start + end + 1
+	next := start + end + utf8.RuneLen(sep)
+	return key[start:next], next
 }
 
-// Splits key into its parts specified by the separator.
+// SplitPath splits the first segment separated by sep off path and returns
+// it together with the rest of path, which starts at the separator after
+// the segment. Separators are collapsed: leading ones are skipped and rest
+// is empty when only separators follow the segment. A path made of
+// separators only is a single segment, and so is any path when sep is
+// empty. Separators may be any number of bytes.
 func SplitPath(path string, sep string) (string, string) {
-	var key string
-	if path == "" {
-		return key, path
-	} else if path == sep {
+	it := Segments(path, sep, SegmentOptions{Collapse: true})
+	if !it.Next() {
 		return path, ""
 	}
-	i := 0
-	for {
-		if i < len(path) {
-			break
+	segment, rest := it.Segment(), it.Rest()
+	if !it.Next() {
+		rest = ""
+	}
+	return segment, rest
+}
+
+// SegmentOptions configures a SegmentIterator.
+type SegmentOptions struct {
+	// Collapse treats runs of separators as one and skips the empty
+	// segments before the first and after the last separator.
+	Collapse bool
+	// Escape, when not zero, makes a separator or escape that follows it
+	// part of the segment.
+	Escape rune
+}
+
+// SegmentIterator is a cursor over the segments of a path. It does not
+// allocate.
+//
+//	it := Segments("usr/local/bin", "/", SegmentOptions{})
+//	for it.Next() {
+//		fmt.Println(it.Segment())
+//	}
+type SegmentIterator struct {
+	path    string
+	sep     string
+	options SegmentOptions
+	// Current segment and the position after it
+	start, end int
+	// Whether the path has been split up to its end
+	done bool
+}
+
+// Segments returns an iterator over the segments of path separated by sep.
+// Without collapsing, the segments are those of strings.Split and an empty
+// path is a single empty segment. An empty sep does not split path.
+func Segments(path, sep string, options SegmentOptions) SegmentIterator {
+	return SegmentIterator{path: path, sep: sep, options: options, start: -1, end: -1}
+}
+
+// Next moves to the next segment and reports whether there is one.
+func (it *SegmentIterator) Next() bool {
+	for !it.done {
+		// The segment starts after the previous one and its separator
+		start := 0
+		if it.end != -1 {
+			start = it.end + len(it.sep)
+		}
+
+		end := it.index(start)
+		if end == -1 {
+			end = len(it.path)
+			it.done = true
+		}
+		it.start, it.end = start, end
+
+		if !it.options.Collapse || start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the index of the first unescaped separator from start on or -1
+func (it *SegmentIterator) index(start int) int {
+	if it.sep == "" {
+		return -1
+	}
+	if it.options.Escape == 0 {
+		if i := strings.Index(it.path[start:], it.sep); i != -1 {
+			return start + i
 		}
-		if string(path[i]) == sep {
-			if i == 0 {
-				return SplitPath(path[1:], sep)
+		return -1
+	}
+
+	for i := start; i < len(it.path); {
+		if r, size := utf8.DecodeRuneInString(it.path[i:]); r == it.options.Escape {
+			// Skip the escape and the rune or separator it escapes
+			i += size
+			if strings.HasPrefix(it.path[i:], it.sep) {
+				i += len(it.sep)
+			} else if i < len(it.path) {
+				_, size = utf8.DecodeRuneInString(it.path[i:])
+				i += size
+			}
+			continue
+		}
+
+		if strings.HasPrefix(it.path[i:], it.sep) {
+			return i
+		}
+		i++
+	}
+	return -1
+}
+
+// Segment returns the current segment as it appears in the path, with
+// escapes left in place.
+func (it *SegmentIterator) Segment() string {
+	if it.start == -1 {
+		return ""
+	}
+	return it.path[it.start:it.end]
+}
+
+// Rest returns the path after the current segment, starting at the
+// separator that ends it.
+func (it *SegmentIterator) Rest() string {
+	if it.end == -1 {
+		return it.path
+	}
+	return it.path[it.end:]
+}
+
+// AppendSegment appends the current segment to dst with its escapes
+// removed and returns the extended slice. It does not allocate when dst
+// has room.
+func (it *SegmentIterator) AppendSegment(dst []byte) []byte {
+	segment := it.Segment()
+	if it.options.Escape == 0 {
+		return append(dst, segment...)
+	}
+
+	for i := 0; i < len(segment); {
+		r, size := utf8.DecodeRuneInString(segment[i:])
+		if r == it.options.Escape && i+size < len(segment) {
+			rest := segment[i+size:]
+			if strings.HasPrefix(rest, it.sep) {
+				dst = append(dst, it.sep...)
+				i += size + len(it.sep)
+				continue
 			}
-			if i > 0 {
-				key = path[:i]
-				path = path[i:]
-				if path == sep {
-					return key, ""
-				}
-				return key, path
+			if next, nextSize := utf8.DecodeRuneInString(rest); next == it.options.Escape {
+				dst = append(dst, rest[:nextSize]...)
+				i += size + nextSize
+				continue
 			}
 		}
-		i += 1
+		dst = append(dst, segment[i:i+size]...)
+		i += size
 	}
-	return path, ""
+	return dst
 }
 
-// Grows int slice with cap
-func growSlice(slice []int, newCap int) []int {
-	if cap(slice) >= newCap {
+// Grows slice to newLen. Appending lets the capacity grow amortized.
+func growSlice[T any](slice []T, newLen int) []T {
+	if len(slice) >= newLen {
 		return slice
 	}
 
-	return append(slice, make([]int, newCap)...)
+	return append(slice, make([]T, newLen-len(slice))...)
 }
 
-// Ensures slice pos is reachable by growing the slice capacity
-func EnsureIndex(s[]int, pos int) []int  {
-	for {
-		if pos + 1 > cap(s) {
-			s = growSlice(s, cap(s) + growInc)
-		} else {
-			break
-		}
+// Ensures slice pos is reachable by growing the slice length
+func EnsureIndex[T any](s []T, pos int) []T {
+	if pos+1 > len(s) {
+		s = growSlice(s, pos+1+growInc)
 	}
 	return s
 }
 
-func ValueFromChar(code int) int  {
-	return code - 'a' + 1
+// Returns the arc code of a key byte. Bytes map onto minCode..maxCode
+// unchanged, which leaves 0 free for the terminator.
+func ValueFromChar(code int) int {
+	return code
 }
 
-func ValueToChar(code int) int  {
-	return code + 'a'  - 1
+// Returns the key byte of an arc code
+func ValueToChar(code int) int {
+	return code
 }
@@ -1,6 +1,7 @@
 package go_tries
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -15,4 +16,163 @@ func TestEnsureIndex(t *testing.T) {
 	if len(arr) < 25 {
 		t.Errorf("array index is not reachable at %v, length is %v", 25, len(arr))
 	}
-}
\ No newline at end of file
+}
+
+func TestSplitPath(t *testing.T) {
+	cases := []struct {
+		path string
+		key  string
+		rest string
+	}{
+		{"", "", ""},
+		{"dog", "dog", ""},
+		{"dog and cat", "dog", " and cat"},
+		{" and cat", "and", " cat"},
+		{"dog ", "dog", ""},
+		{" ", " ", ""},
+		{"   ", "   ", ""},
+		{"dog   and  cat", "dog", "   and  cat"},
+		{"  dog  ", "dog", ""},
+	}
+
+	for _, c := range cases {
+		key, rest := SplitPath(c.path, " ")
+		if key != c.key || rest != c.rest {
+			t.Errorf("expected SplitPath for %q to be (%q, %q), got (%q, %q)", c.path, c.key, c.rest, key, rest)
+		}
+	}
+}
+
+func TestSplitPathSeparators(t *testing.T) {
+	cases := []struct {
+		path string
+		sep  string
+		key  string
+		rest string
+	}{
+		{"a::b::c", "::", "a", "::b::c"},
+		{"::::a::b", "::", "a", "::b"},
+		{"a:b::c", "::", "a:b", "::c"},
+		{"a::", "::", "a", ""},
+		{"αβγ→δ", "→", "αβγ", "→δ"},
+		{"日本語、中文", "、", "日本語", "、中文"},
+		{"a<->b", "<->", "a", "<->b"},
+		{"a/b", "", "a/b", ""},
+	}
+
+	for _, c := range cases {
+		key, rest := SplitPath(c.path, c.sep)
+		if key != c.key || rest != c.rest {
+			t.Errorf("expected SplitPath for %q on %q to be (%q, %q), got (%q, %q)", c.path, c.sep, c.key, c.rest, key, rest)
+		}
+	}
+}
+
+func TestSegments(t *testing.T) {
+	cases := []struct {
+		name      string
+		path      string
+		sep       string
+		options   SegmentOptions
+		segments  []string
+		unescaped []string
+	}{
+		{"split", "a/b/c", "/", SegmentOptions{}, []string{"a", "b", "c"}, nil},
+		{"empty path", "", "/", SegmentOptions{}, []string{""}, nil},
+		{"empty segments", "/a//b/", "/", SegmentOptions{}, []string{"", "a", "", "b", ""}, nil},
+		{"collapse", "/a//b/", "/", SegmentOptions{Collapse: true}, []string{"a", "b"}, nil},
+		{"collapse empty path", "", "/", SegmentOptions{Collapse: true}, nil, nil},
+		{"collapse separators only", "///", "/", SegmentOptions{Collapse: true}, nil, nil},
+		{"multi byte", "a::b::::c", "::", SegmentOptions{}, []string{"a", "b", "", "c"}, nil},
+		{"multi rune", "x→y→→z", "→", SegmentOptions{Collapse: true}, []string{"x", "y", "z"}, nil},
+		{"empty separator", "a/b", "", SegmentOptions{}, []string{"a/b"}, nil},
+		{"escape", `a\/b/c`, "/", SegmentOptions{Escape: '\\'}, []string{`a\/b`, "c"}, []string{"a/b", "c"}},
+		{"escape escape", `a\\/b`, "/", SegmentOptions{Escape: '\\'}, []string{`a\\`, "b"}, []string{`a\`, "b"}},
+		{"escape other", `a\x/b`, "/", SegmentOptions{Escape: '\\'}, []string{`a\x`, "b"}, []string{`a\x`, "b"}},
+		{"escape at end", `a/b\`, "/", SegmentOptions{Escape: '\\'}, []string{"a", `b\`}, []string{"a", `b\`}},
+		{"escape multi byte", "a^::b::c", "::", SegmentOptions{Escape: '^'}, []string{"a^::b", "c"}, []string{"a::b", "c"}},
+		{"escape rune", "a§/b", "/", SegmentOptions{Escape: '§'}, []string{"a§/b"}, []string{"a/b"}},
+	}
+
+	for _, c := range cases {
+		var segments, unescaped []string
+		it := Segments(c.path, c.sep, c.options)
+		for it.Next() {
+			segments = append(segments, it.Segment())
+			unescaped = append(unescaped, string(it.AppendSegment(nil)))
+		}
+		if c.unescaped == nil {
+			c.unescaped = c.segments
+		}
+
+		if !reflect.DeepEqual(segments, c.segments) {
+			t.Errorf("%s: expected segments %q, got %q", c.name, c.segments, segments)
+		}
+		if !reflect.DeepEqual(unescaped, c.unescaped) {
+			t.Errorf("%s: expected unescaped segments %q, got %q", c.name, c.unescaped, unescaped)
+		}
+	}
+}
+
+func TestSegmentsRest(t *testing.T) {
+	it := Segments("a::b", "::", SegmentOptions{})
+	if it.Rest() != "a::b" {
+		t.Errorf("expected Rest before Next to be %q, got %q", "a::b", it.Rest())
+	}
+	it.Next()
+	if it.Rest() != "::b" {
+		t.Errorf("expected Rest to be %q, got %q", "::b", it.Rest())
+	}
+	it.Next()
+	if it.Rest() != "" {
+		t.Errorf("expected Rest to be %q, got %q", "", it.Rest())
+	}
+}
+
+func TestSegmentsAllocs(t *testing.T) {
+	buf := make([]byte, 0, 64)
+	allocs := testing.AllocsPerRun(100, func() {
+		it := Segments(`usr/lo\/cal//bin`, "/", SegmentOptions{Collapse: true, Escape: '\\'})
+		for it.Next() {
+			buf = it.AppendSegment(buf[:0])
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("expected iterating segments not to allocate, got %v allocations", allocs)
+	}
+}
+
+func TestNextWord(t *testing.T) {
+	cases := []struct {
+		key     string
+		start   int
+		sep     rune
+		segment string
+		next    int
+	}{
+		{"", 0, ' ', "", -1},
+		{"dog and cat", 0, ' ', "dog ", 4},
+		{"dog and cat", 4, ' ', "and ", 8},
+		{"dog and cat", 8, ' ', "cat", -1},
+		{"dog", 3, ' ', "", -1},
+		{"dog", -1, ' ', "", -1},
+		{"a→b", 0, '→', "a→", 4},
+		{"a→b", 4, '→', "b", -1},
+	}
+
+	for _, c := range cases {
+		segment, next := NextWord(c.key, c.start, c.sep)
+		if segment != c.segment || next != c.next {
+			t.Errorf("expected NextWord for %q at %v to be (%q, %v), got (%q, %v)", c.key, c.start, c.segment, c.next, segment, next)
+		}
+	}
+}
+
+func BenchmarkSplitPath(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for part, rest := SplitPath(phrases[i%len(phrases)], " "); rest != ""; part, rest = SplitPath(rest, " ") {
+			_ = part
+		}
+	}
+}